@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Light to dark, so the heatmap still reads without color
+var heatGlyphs = []string{"·", "░", "▒", "▓", "█"}
+
+// 0 for no entries, then 1-4 relative to the busiest day
+func heatLevel(count int, busiest int) int {
+	if count == 0 || busiest == 0 {
+		return 0
+	}
+	return (count*4 + busiest - 1) / busiest
+}
+
+// A GitHub-style year of weeks, Monday at the top. Returns the lines so the
+// prompt can redraw it with a message underneath
+func renderCalendar(year int, counts map[time.Time]int) []string {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	// Back up to the Monday of the first week
+	first := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	weeks := int(end.Sub(first).Hours()/24)/7 + 1
+
+	busiest, total, days := 0, 0, 0
+	for date, count := range counts {
+		if date.Year() != year {
+			continue
+		}
+		total += count
+		days++
+		if count > busiest {
+			busiest = count
+		}
+	}
+
+	// Month names over the week their first day falls in
+	header := []rune(strings.Repeat(" ", 4+weeks*2))
+	for month := time.January; month <= time.December; month++ {
+		firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		column := 4 + int(firstOfMonth.Sub(first).Hours()/24)/7*2
+		copy(header[column:], []rune(firstOfMonth.Format("Jan")))
+	}
+	lines := []string{strings.TrimRight(string(header), " ")}
+
+	labels := []string{"Mon", "", "Wed", "", "Fri", "", "Sun"}
+	for weekday := 0; weekday < 7; weekday++ {
+		var line strings.Builder
+		line.WriteString(fmt.Sprintf("%-4s", labels[weekday]))
+		for week := 0; week < weeks; week++ {
+			day := first.AddDate(0, 0, week*7+weekday)
+			if day.Year() != year {
+				line.WriteString("  ")
+				continue
+			}
+			level := heatLevel(counts[day], busiest)
+			if level == 0 {
+				line.WriteString(theme.Faint + heatGlyphs[0] + theme.Reset + " ")
+			} else {
+				line.WriteString(theme.Heat + heatGlyphs[level] + theme.Reset + " ")
+			}
+		}
+		lines = append(lines, line.String())
+	}
+
+	legend := "Less"
+	for level, glyph := range heatGlyphs {
+		if level == 0 {
+			legend += " " + theme.Faint + glyph + theme.Reset
+		} else {
+			legend += " " + theme.Heat + glyph + theme.Reset
+		}
+	}
+	lines = append(lines, "", legend+" More    "+strconv.Itoa(total)+" entries on "+strconv.Itoa(days)+" days")
+	return lines
+}
+
+// Parses MM/DD (in the calendar's year) or MM/DD/YYYY
+func parseCalendarDay(input string, year int) (time.Time, error) {
+	if day, err := time.Parse("01/02/2006", input); err == nil {
+		return day, nil
+	}
+	day, err := time.Parse("01/02", input)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected MM/DD or MM/DD/YYYY, got %q", input)
+	}
+	return time.Date(year, day.Month(), day.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+func showCalendar(args []string) {
+	calendarCmd := flag.NewFlagSet("calendar", flag.ExitOnError)
+	year := calendarCmd.Int("year", time.Now().Year(), "Year to show")
+	inclusive := calendarCmd.Bool("i", false, "Inclusive search: count entries which include ANY of the provided tags (default: all tags must match)")
+	calendarCmd.Parse(args)
+
+	searchTags := calendarCmd.Args()
+	searchTagSet := make(map[string]bool)
+	for i := range searchTags {
+		searchTags[i] = normalizeTag(searchTags[i])
+		searchTagSet[searchTags[i]] = true
+	}
+
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+	// Writing activity, so the originals rather than merges of them
+	entries = searchEntries(entries, searchTagSet, searchOptions{Inclusive: *inclusive, OriginalsOnly: true})
+
+	counts := make(map[time.Time]int)
+	byDay := make(map[time.Time][]Entry)
+	for _, entry := range entries {
+		date, err := entryDate(entry)
+		if err != nil {
+			continue
+		}
+		counts[date]++
+		byDay[date] = append(byDay[date], entry)
+	}
+
+	title := "=========CALENDAR " + strconv.Itoa(*year)
+	if len(searchTags) > 0 {
+		title += " (" + strings.Join(searchTags, ",") + ")"
+	}
+	lines := renderCalendar(*year, counts)
+
+	// Only offer to pick a day when someone is there to pick one
+	if !stdoutIsTerminal() {
+		fmt.Println(theme.Title, title, theme.Reset)
+		fmt.Println(strings.Join(lines, "\n"))
+		return
+	}
+
+	message := ""
+	for {
+		clearTerminal()
+		fmt.Println(theme.Title, title, theme.Reset)
+		fmt.Println("")
+		fmt.Println(strings.Join(lines, "\n"))
+		fmt.Println("")
+		if message != "" {
+			fmt.Println(theme.Info, message, theme.Reset)
+		}
+		answer, err := readAnswer(theme.Option + "Day to list: " + theme.Reset + "MM/DD[/YYYY], q to quit: ")
+		if err != nil {
+			return
+		}
+		input := strings.TrimSpace(answer)
+		if input == "" || input == "q" {
+			return
+		}
+		day, err := parseCalendarDay(input, *year)
+		if err != nil {
+			message = err.Error()
+			continue
+		}
+		if len(byDay[day]) == 0 {
+			message = "No entries on " + day.Format("01/02/2006")
+			continue
+		}
+
+		resultsList = byDay[day]
+		optionsPrompt("RESULTS", resultsList, append([]string{day.Format("01/02/2006")}, searchTags...), "")
+		return
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Flags offered for each subcommand, kept next to the completion code so
+// the scripts never need regenerating when a flag is added
+var completionFlags = map[string][]string{
+	"new":      {"-t"},
+	"find":     {"-i", "-f", "-a", "-d", "-o", "-from", "-to", "-save"},
+	"tags":     {"-f"},
+	"random":   {"-i", "-w"},
+	"review":   {"-all", "-n"},
+	"log":      {"-p"},
+	"export":   {"-out", "-e", "-sections"},
+	"import":   {"-dry-run"},
+	"serve":    {"-addr", "-token"},
+	"init":     {"-force", "-local"},
+	"stats":    {"-json", "-days", "-top"},
+	"calendar": {"-year", "-i"},
+	"links":    {"-broken"},
+	"graph":    {"-format", "-out", "-tag", "-e"},
+	"related":  {"-n"},
+	"saved":    {"-rm"},
+	"tag":      {"-query"},
+}
+
+const bashCompletion = `# bash completion for journalz_ro
+_journalz_ro() {
+    local IFS=$'\n'
+    COMPREPLY=( $(journalz_ro __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null) )
+}
+complete -F _journalz_ro journalz_ro
+`
+
+const zshCompletion = `#compdef journalz_ro
+# zsh completion for journalz_ro
+_journalz_ro() {
+    local -a candidates
+    candidates=("${(@f)$(journalz_ro __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    compadd -a candidates
+}
+compdef _journalz_ro journalz_ro
+`
+
+const fishCompletion = `# fish completion for journalz_ro
+function __journalz_ro_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    journalz_ro __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c journalz_ro -f -a '(__journalz_ro_complete)'
+`
+
+func printCompletionScript(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: journalz_ro completion bash|zsh|fish")
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	default:
+		fmt.Println("Unknown shell: " + args[0] + ". Use bash, zsh or fish.")
+		os.Exit(1)
+	}
+}
+
+// Hidden __complete subcommand. args are the words after the program name,
+// the last one being the (possibly empty) word under the cursor
+func complete(args []string) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	current := args[len(args)-1]
+	previous := args[:len(args)-1]
+
+	for _, candidate := range completionCandidates(previous, current) {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Println(candidate)
+		}
+	}
+}
+func completionCandidates(previous []string, current string) []string {
+	if len(previous) == 0 {
+		var names []string
+		for _, sub := range subcommands {
+			names = append(names, strings.Trim(sub, "'"))
+		}
+		return append(names, "completion")
+	}
+
+	if len(previous) == 1 && previous[0] == "-j" {
+		return journalNames()
+	}
+	if len(previous) == 1 && previous[0] == "-color" {
+		return []string{"auto", "always", "never"}
+	}
+
+	subcommand := previous[0]
+	last := previous[len(previous)-1]
+	// Everything after tag ... -query is a find query
+	if subcommand == "tag" && contains(previous, "-query") {
+		i := len(previous)
+		for previous[i-1] != "-query" {
+			i--
+		}
+		return completionCandidates(append([]string{"find"}, previous[i:]...), current)
+	}
+	if strings.HasPrefix(current, "-") {
+		return completionFlags[subcommand]
+	}
+
+	switch subcommand {
+	case "new":
+		if last == "-t" {
+			return templateNames()
+		}
+	case "find":
+		if strings.HasPrefix(current, "@") {
+			var names []string
+			for _, name := range savedSearchNames() {
+				names = append(names, "@"+name)
+			}
+			return names
+		}
+		if last != "-from" && last != "-to" && last != "-save" {
+			return tagNames()
+		}
+	case "saved":
+		if last == "-rm" {
+			return savedSearchNames()
+		}
+	case "tag":
+		switch {
+		case len(previous) == 1:
+			return []string{"add", "remove"}
+		case len(previous) == 2:
+			return tagNames()
+		}
+		return append(entryNames(), mergeNames()...)
+	case "calendar":
+		if last != "-year" {
+			return tagNames()
+		}
+	case "graph":
+		switch last {
+		case "-format":
+			return []string{"dot", "json", "graphml"}
+		case "-tag":
+			return tagNames()
+		}
+	case "review":
+		if last != "-n" {
+			return tagNames()
+		}
+	case "log", "links", "related", "suggest-tags":
+		return append(entryNames(), mergeNames()...)
+	case "import":
+		if len(previous) == 1 || (len(previous) == 2 && strings.HasPrefix(last, "-")) {
+			return []string{"jrnl", "dayone", "obsidian"}
+		}
+	case "export":
+		if len(previous) == 1 {
+			return []string{"html", "epub"}
+		}
+		if previous[1] == "epub" && last != "-out" {
+			return append(mergeNames(), tagNames()...)
+		}
+	case "random":
+		if last == "-w" {
+			return []string{"old", "unseen"}
+		}
+		return tagNames()
+	case "config":
+		if len(previous) == 1 {
+			return []string{"get", "set", "edit", "path"}
+		}
+		if len(previous) == 2 && (previous[1] == "get" || previous[1] == "set") {
+			return configKeys
+		}
+	case "completion":
+		if len(previous) == 1 {
+			return []string{"bash", "zsh", "fish"}
+		}
+	}
+	return nil
+}
+
+// Tab completion for the results and merge list prompts. Searches typed
+// there complete like find does on the command line
+func promptCompletions(title string) func(before string) []string {
+	return func(before string) []string {
+		words := strings.Fields(before)
+		current := ""
+		if len(words) > 0 && !strings.HasSuffix(before, " ") {
+			current = words[len(words)-1]
+			words = words[:len(words)-1]
+		}
+
+		if len(words) == 0 {
+			if title == "MERGE LIST" {
+				return []string{"m", "d", "b", "t+", "t-", "q"}
+			}
+			commands := []string{"r", "n", "a", "w", "d", "s", "v", "t+", "t-", "q"}
+			for _, name := range savedSearchNames() {
+				commands = append(commands, "@"+name)
+			}
+			return commands
+		}
+		if (words[0] == "t+" || words[0] == "t-") && len(words) == 1 {
+			return tagNames()
+		}
+		if title == "RESULTS" {
+			switch command := strings.ToLower(words[0]); {
+			case command == "r", command == "n", strings.HasPrefix(command, "@"):
+				return completionCandidates(append([]string{"find"}, words[1:]...), current)
+			}
+		}
+		return nil
+	}
+}
+
+// Canonical tags in use, their parents and every alias from the vocabulary
+func tagNames() []string {
+	set := make(map[string]bool)
+	entries, err := loadUnlockedEntries()
+	if err == nil {
+		for _, entry := range entries {
+			for _, tag := range normalizeTags(entry.Tags) {
+				for _, name := range tagAncestors(tag) {
+					set[name] = true
+				}
+			}
+		}
+	}
+	for alias, canonical := range tagAliases {
+		set[alias] = true
+		set[canonical] = true
+	}
+
+	var names []string
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Templates are the .md files next to the default template
+func templateNames() []string {
+	names := markdownNames(filepath.Dir(TEMPLATE))
+	if !contains(names, "entry_template") {
+		names = append(names, "entry_template")
+	}
+	return names
+}
+
+// Names of the entries in SAVEDIR, without the .md extension
+func entryNames() []string {
+	return markdownNames(SAVEDIR)
+}
+
+// Names of the merge entries, without the .md extension
+func mergeNames() []string {
+	return markdownNames(MERGE_DIR)
+}
+func markdownNames(dir string) []string {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".md") {
+			names = append(names, strings.TrimSuffix(file.Name(), ".md"))
+		}
+	}
+	return names
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	Journal        string
+	DefaultJournal string
+	SaveDir        string
+	MergeDir       string
+	TemplateDir    string
+	Template       string
+	StartPos       int
+	TerminalApp    string
+	Editor         string
+	GitAutoCommit  bool
+	Encrypt        string
+	EncryptTag     string
+	Theme          string
+}
+
+// Every key the config file understands, in the order `config get` lists them.
+// Each can be overridden with a JOURNALZ_<KEY> environment variable
+var configKeys = []string{
+	"SAVE_DIR",
+	"MERGE_DIR",
+	"TEMPLATE_DIR",
+	"TEMPLATE",
+	"START_POS",
+	"TERMINAL_APP",
+	"EDITOR",
+	"GIT_AUTOCOMMIT",
+	"ENCRYPT",
+	"ENCRYPT_TAG",
+	"THEME",
+	"DEFAULT_JOURNAL",
+}
+
+// A parsed config file: the top-level keys, which make up the "default"
+// journal and are inherited by the others, then one [name] section per
+// named journal
+type configFile struct {
+	Values   map[string]string
+	Journals map[string]map[string]string
+	Names    []string
+}
+
+var journalNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Every journal in the file, "default" first
+func (f configFile) journalNames() []string {
+	names := []string{"default"}
+	for _, name := range f.Names {
+		if name != "default" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// $XDG_CONFIG_HOME/journal_zro, or ~/.config/journal_zro
+func configHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "journal_zro")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", "journal_zro")
+}
+
+// $XDG_DATA_HOME/journal_zro, or ~/.local/share/journal_zro
+func dataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "journal_zro")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".local", "share", "journal_zro")
+}
+
+// Where the config file lives, JOURNALZ_CONFIG wins
+func defaultConfigPath() string {
+	if path := os.Getenv("JOURNALZ_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(configHome(), "config.cfg")
+}
+
+// Expands ~ and $VARS. Relative paths are taken relative to base
+func expandPath(value string, base string) string {
+	value = os.ExpandEnv(value)
+	home := os.Getenv("HOME")
+	if value == "~" {
+		return home
+	}
+	if strings.HasPrefix(value, "~/") {
+		return filepath.Join(home, value[2:])
+	}
+	if filepath.IsAbs(value) {
+		return filepath.Clean(value)
+	}
+	return filepath.Join(base, value)
+}
+
+// Reads key=value lines and [journal] headers, rejecting anything it
+// doesn't understand
+func readConfigFile(path string) (configFile, error) {
+	config := configFile{Values: make(map[string]string), Journals: make(map[string]map[string]string)}
+	values := config.Values
+	section := ""
+
+	file, err := os.Open(path)
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+
+	// Read the file line by line
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Ignore empty lines and comments
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// [name] starts the settings of a named journal
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if !journalNameRegex.MatchString(section) {
+				return config, fmt.Errorf("%s:%d: journal names can only use letters, digits, - and _, got %q", path, lineNumber, section)
+			}
+			if section == "local" {
+				return config, fmt.Errorf("%s:%d: local is reserved for project journals, pick another name", path, lineNumber)
+			}
+			if _, ok := config.Journals[section]; ok {
+				return config, fmt.Errorf("%s:%d: journal %s is defined twice", path, lineNumber, section)
+			}
+			values = make(map[string]string)
+			config.Journals[section] = values
+			config.Names = append(config.Names, section)
+			continue
+		}
+
+		// Split the line into key=value
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return config, fmt.Errorf("%s:%d: expected KEY=value or [journal], got %q", path, lineNumber, line)
+		}
+
+		// Trim spaces and store key-value pair
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if !contains(configKeys, key) {
+			return config, fmt.Errorf("%s:%d: unknown key %s (known keys: %s)", path, lineNumber, key, strings.Join(configKeys, ", "))
+		}
+		if key == "DEFAULT_JOURNAL" && section != "" {
+			return config, fmt.Errorf("%s:%d: DEFAULT_JOURNAL belongs at the top of the file, not in [%s]", path, lineNumber, section)
+		}
+		values[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// Turns the file's values for one journal into a Config, filling in
+// defaults, resolving paths and checking everything up front so later code
+// can trust it. An empty journal means DEFAULT_JOURNAL
+func buildConfig(file configFile, journal string) (Config, error) {
+	var c Config
+	c.DefaultJournal = "default"
+	if file.Values["DEFAULT_JOURNAL"] != "" {
+		c.DefaultJournal = file.Values["DEFAULT_JOURNAL"]
+		if !contains(file.journalNames(), c.DefaultJournal) {
+			return c, fmt.Errorf("DEFAULT_JOURNAL is %s, but there is no [%s] journal", c.DefaultJournal, c.DefaultJournal)
+		}
+	}
+	c.Journal = journal
+	if c.Journal == "" {
+		c.Journal = c.DefaultJournal
+	}
+	if c.Journal == "local" {
+		return c, fmt.Errorf("local is reserved for project journals, pick another name")
+	}
+
+	values := make(map[string]string)
+	for key, value := range file.Values {
+		values[key] = value
+	}
+	if section, ok := file.Journals[c.Journal]; ok {
+		// Every other setting is inherited, but sharing a journal's files
+		// would defeat the point of having two
+		delete(values, "SAVE_DIR")
+		delete(values, "MERGE_DIR")
+		for key, value := range section {
+			values[key] = value
+		}
+		if c.Journal != "default" && values["SAVE_DIR"] == "" {
+			return c, fmt.Errorf("journal %s needs its own SAVE_DIR", c.Journal)
+		}
+	} else if c.Journal != "default" {
+		return c, fmt.Errorf("unknown journal %s (journals: %s)", c.Journal, strings.Join(file.journalNames(), ", "))
+	}
+
+	for _, key := range configKeys {
+		if value := os.Getenv("JOURNALZ_" + key); value != "" {
+			values[key] = value
+		}
+	}
+	home := os.Getenv("HOME")
+
+	// SAVE_DIR used to be relative to $HOME, so relative paths still are
+	c.SaveDir = filepath.Join(home, "Documents", "Journal_Zro")
+	if values["SAVE_DIR"] != "" {
+		c.SaveDir = expandPath(values["SAVE_DIR"], home)
+	}
+
+	c.MergeDir = filepath.Join(c.SaveDir, ".merges")
+	if values["MERGE_DIR"] != "" {
+		c.MergeDir = expandPath(values["MERGE_DIR"], c.SaveDir)
+	}
+
+	c.TemplateDir = defaultTemplateDir()
+	if values["TEMPLATE_DIR"] != "" {
+		c.TemplateDir = expandPath(values["TEMPLATE_DIR"], configHome())
+	}
+
+	c.Template = defaultTemplate
+	if values["TEMPLATE"] != "" {
+		c.Template = values["TEMPLATE"]
+		if !strings.HasSuffix(c.Template, ".md") {
+			c.Template += ".md"
+		}
+	}
+
+	c.StartPos = 4
+	if values["START_POS"] != "" {
+		startPos, err := strconv.Atoi(values["START_POS"])
+		if err != nil || startPos < 1 {
+			return c, fmt.Errorf("START_POS must be a line number, got %q", values["START_POS"])
+		}
+		c.StartPos = startPos
+	}
+
+	c.TerminalApp = "alacritty"
+	if values["TERMINAL_APP"] != "" {
+		c.TerminalApp = values["TERMINAL_APP"]
+	}
+
+	c.Editor = "nvim"
+	if values["EDITOR"] != "" {
+		c.Editor = values["EDITOR"]
+	}
+
+	if values["GIT_AUTOCOMMIT"] != "" {
+		autoCommit, err := strconv.ParseBool(values["GIT_AUTOCOMMIT"])
+		if err != nil {
+			return c, fmt.Errorf("GIT_AUTOCOMMIT must be true or false, got %q", values["GIT_AUTOCOMMIT"])
+		}
+		c.GitAutoCommit = autoCommit
+	}
+
+	c.Encrypt = "off"
+	if values["ENCRYPT"] != "" {
+		c.Encrypt = strings.ToLower(values["ENCRYPT"])
+	}
+	c.EncryptTag = values["ENCRYPT_TAG"]
+	switch c.Encrypt {
+	case "off", "all":
+	case "tag":
+		if c.EncryptTag == "" {
+			return c, fmt.Errorf("ENCRYPT=tag needs ENCRYPT_TAG to say which tag to encrypt")
+		}
+	default:
+		return c, fmt.Errorf("ENCRYPT must be off, all or tag, got %q", values["ENCRYPT"])
+	}
+
+	c.Theme = "default"
+	if values["THEME"] != "" {
+		c.Theme = values["THEME"]
+	}
+
+	return c, nil
+}
+
+// The effective value of a key, after defaults and environment overrides
+func (c Config) Get(key string) (string, error) {
+	switch key {
+	case "SAVE_DIR":
+		return c.SaveDir, nil
+	case "MERGE_DIR":
+		return c.MergeDir, nil
+	case "TEMPLATE_DIR":
+		return c.TemplateDir, nil
+	case "TEMPLATE":
+		return c.Template, nil
+	case "START_POS":
+		return strconv.Itoa(c.StartPos), nil
+	case "TERMINAL_APP":
+		return c.TerminalApp, nil
+	case "EDITOR":
+		return c.Editor, nil
+	case "GIT_AUTOCOMMIT":
+		return strconv.FormatBool(c.GitAutoCommit), nil
+	case "ENCRYPT":
+		return c.Encrypt, nil
+	case "ENCRYPT_TAG":
+		return c.EncryptTag, nil
+	case "THEME":
+		return c.Theme, nil
+	case "DEFAULT_JOURNAL":
+		return c.DefaultJournal, nil
+	}
+	return "", fmt.Errorf("unknown key %s (known keys: %s)", key, strings.Join(configKeys, ", "))
+}
+
+// Loads the selected journal's config, creating the file from the
+// built-in default.cfg on first run
+func loadConfig() (Config, error) {
+	if err := ensureConfigFile(); err != nil {
+		return Config{}, err
+	}
+
+	file, err := readConfigFile(configPath)
+	if err != nil {
+		return Config{}, err
+	}
+	// -j always wins over a project's own journal
+	if journalName == "" {
+		if path, ok := findLocalJournal(); ok {
+			return buildLocalConfig(file, path)
+		}
+	}
+	c, err := buildConfig(file, journalName)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	return c, nil
+}
+
+func ensureConfigFile() error {
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+	if !fileExists(configPath) {
+		if err := os.WriteFile(configPath, defaultFile("default.cfg"), 0644); err != nil {
+			return fmt.Errorf("could not create %s: %w", configPath, err)
+		}
+	}
+	return nil
+}
+
+// Points the globals the rest of the program uses at the configured paths
+func applyConfig(c Config) {
+	cfg = c
+	SAVEDIR = c.SaveDir + "/"
+	MERGE_DIR = c.MergeDir + "/"
+	TEMPLATE = filepath.Join(c.TemplateDir, c.Template)
+}
+
+// config get|set|edit|path
+func configCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: journalz_ro config get [KEY] | set KEY VALUE | edit | path")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "path":
+		fmt.Println(configPath)
+	case "get":
+		c, err := loadConfig()
+		if err != nil {
+			fmt.Println("Error loading config file:", err)
+			os.Exit(1)
+		}
+		if len(args) > 1 {
+			value, err := c.Get(strings.ToUpper(args[1]))
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			fmt.Println(value)
+			return
+		}
+		for _, key := range configKeys {
+			value, _ := c.Get(key)
+			fmt.Println(key + "=" + value)
+		}
+	case "set":
+		if len(args) < 3 {
+			fmt.Println("Usage: journalz_ro config set KEY VALUE")
+			os.Exit(1)
+		}
+		if err := setConfigValue(strings.ToUpper(args[1]), strings.Join(args[2:], " ")); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	case "edit":
+		if _, err := loadConfig(); err != nil {
+			// Still open it, editing is how a broken config gets fixed
+			fmt.Println("Current config has a problem:", err)
+		}
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "nvim"
+		}
+		cmd := exec.Command(editor, configPath)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Println("Error running "+editor, err)
+			os.Exit(1)
+		}
+		if _, err := loadConfig(); err != nil {
+			fmt.Println("Error in config file:", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Println("Unknown config command: " + args[0] + ". Use get, set, edit or path.")
+		os.Exit(1)
+	}
+}
+
+// Replaces the key's line in the config file (or adds one), keeping
+// comments and everything else as they were. With -j the key goes in that
+// journal's [section], created if needed. Refuses values that don't validate
+func setConfigValue(key string, value string) error {
+	if !contains(configKeys, key) {
+		return fmt.Errorf("unknown key %s (known keys: %s)", key, strings.Join(configKeys, ", "))
+	}
+	if err := ensureConfigFile(); err != nil {
+		return err
+	}
+	file, err := readConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	section := ""
+	if journalName != "" && key != "DEFAULT_JOURNAL" {
+		if _, ok := file.Journals[journalName]; ok || journalName != "default" {
+			section = journalName
+		}
+	}
+	if section == "" {
+		file.Values[key] = value
+	} else {
+		if file.Journals[section] == nil {
+			file.Journals[section] = make(map[string]string)
+			file.Names = append(file.Names, section)
+		}
+		file.Journals[section][key] = value
+	}
+	if _, err := buildConfig(file, journalName); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+
+	// The lines belonging to the section: from its header to the next one
+	start, end := 0, len(lines)
+	if section != "" {
+		start = -1
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "["+section+"]" {
+				start = i + 1
+				break
+			}
+		}
+		if start == -1 {
+			lines = append(lines, "", "["+section+"]", key+"="+value)
+			return os.WriteFile(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+		}
+	}
+	for i := start; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "[") {
+			end = i
+			break
+		}
+	}
+
+	for i := start; i < end; i++ {
+		parts := strings.SplitN(strings.TrimSpace(lines[i]), "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			lines[i] = key + "=" + value
+			return os.WriteFile(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+		}
+	}
+
+	// Not there yet, add it after the section's last setting, above the
+	// comments leading into the next section
+	insert := end
+	for insert > start {
+		line := strings.TrimSpace(lines[insert-1])
+		if line != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+		insert--
+	}
+	lines = append(lines[:insert], append([]string{key + "=" + value}, lines[insert:]...)...)
+	return os.WriteFile(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, "config.cfg")
+	oldPath, oldJournal := configPath, journalName
+	defer func() { configPath, journalName = oldPath, oldJournal }()
+	configPath = path
+
+	config := `START_POS=4
+TERMINAL_APP=alacritty
+
+#Named journals
+#[work]
+#SAVE_DIR=~/Documents/Work_Journal
+
+[work]
+SAVE_DIR=~/Work
+`
+	tests := []struct {
+		name    string
+		journal string
+		key     string
+		value   string
+		want    string
+	}{
+		{"new top-level key goes above the section comments", "", "EDITOR", "vim", `START_POS=4
+TERMINAL_APP=alacritty
+EDITOR=vim
+
+#Named journals
+#[work]
+#SAVE_DIR=~/Documents/Work_Journal
+
+[work]
+SAVE_DIR=~/Work
+`},
+		{"existing key is replaced in place", "", "START_POS", "6", `START_POS=6
+TERMINAL_APP=alacritty
+
+#Named journals
+#[work]
+#SAVE_DIR=~/Documents/Work_Journal
+
+[work]
+SAVE_DIR=~/Work
+`},
+		{"key for a journal goes in its section", "work", "START_POS", "2", config + "START_POS=2\n"},
+	}
+	for _, test := range tests {
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		journalName = test.journal
+		if err := setConfigValue(test.key, test.value); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
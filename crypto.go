@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// Encrypted entries keep their name and start with this header, followed by
+// the GCM nonce and the sealed markdown
+var encryptedHeader = []byte("JZENC1\n")
+
+const encryptionFile = ".encryption.json"
+const keyCheckText = "journalz_ro"
+
+// Salt for the journal key and a sealed known value to catch typos in the passphrase
+type encryptionState struct {
+	Salt  []byte `json:"salt"`
+	Check []byte `json:"check"`
+}
+
+// Derived once per run
+var journalKey []byte
+
+// Off where a passphrase prompt would get in the way, like completion.
+// Encrypted entries are then left out of loadEntries unless the key is
+// already known
+var askPassphrase = true
+
+// ENCRYPT is off, all, or tag (only entries tagged ENCRYPT_TAG)
+func encryptionMode() string {
+	return cfg.Encrypt
+}
+func shouldEncrypt(tags []string) bool {
+	switch encryptionMode() {
+	case "all":
+		return true
+	case "tag":
+		return matchesTags(tags, map[string]bool{normalizeTag(cfg.EncryptTag): true}, false)
+	}
+	return false
+}
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedHeader)
+}
+func isEncryptedFile(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && isEncrypted(data)
+}
+
+// Whether encrypted entries can be read without asking for the passphrase
+func journalKeyAtHand() bool {
+	return journalKey != nil || os.Getenv("JOURNALZ_PASSPHRASE") != ""
+}
+
+// Reads an entry, decrypting it if needed
+func readEntryFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isEncrypted(data) {
+		return data, nil
+	}
+
+	key, err := getJournalKey(false)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := openSealed(key, data[len(encryptedHeader):])
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %s: %w", filepath.Base(path), err)
+	}
+	return plain, nil
+}
+
+// Writes an entry, encrypting it if asked to
+func writeEntryFile(path string, data []byte, encrypt bool) error {
+	if !encrypt {
+		return os.WriteFile(path, data, 0644)
+	}
+
+	key, err := getJournalKey(true)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(key, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(append([]byte{}, encryptedHeader...), sealed...), 0600)
+}
+func seal(key []byte, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+func openSealed(key []byte, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("file is truncated")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted file")
+	}
+	return plain, nil
+}
+func deriveKey(passphrase []byte, salt []byte) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+}
+
+// Derives the journal key from the passphrase, setting up the salt the first
+// time something is encrypted
+func getJournalKey(create bool) ([]byte, error) {
+	if journalKey != nil {
+		return journalKey, nil
+	}
+
+	statePath := filepath.Join(SAVEDIR, encryptionFile)
+	var state encryptionState
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		if !create {
+			return nil, fmt.Errorf("encrypted entry found but %s is missing", statePath)
+		}
+		return setupJournalKey(statePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid encryption file: %w", err)
+	}
+
+	passphrase, err := readPassphrase("Journal passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(passphrase, state.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := openSealed(key, state.Check); err != nil || string(check) != keyCheckText {
+		return nil, errors.New("wrong passphrase")
+	}
+	journalKey = key
+	return journalKey, nil
+}
+func setupJournalKey(statePath string) ([]byte, error) {
+	passphrase, err := readPassphrase("New journal passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("JOURNALZ_PASSPHRASE") == "" {
+		confirm, err := readPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, confirm) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+
+	var state encryptionState
+	state.Salt = make([]byte, 16)
+	if _, err := rand.Read(state.Salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(passphrase, state.Salt)
+	if err != nil {
+		return nil, err
+	}
+	state.Check, err = seal(key, []byte(keyCheckText))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(statePath, data, 0600); err != nil {
+		return nil, err
+	}
+	journalKey = key
+	return journalKey, nil
+}
+
+// JOURNALZ_PASSPHRASE wins, otherwise ask on the terminal without echo
+func readPassphrase(prompt string) ([]byte, error) {
+	if passphrase := os.Getenv("JOURNALZ_PASSPHRASE"); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if !askPassphrase {
+		return nil, errors.New("entry is encrypted, set JOURNALZ_PASSPHRASE to read it here")
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.New("no terminal to ask for the passphrase, set JOURNALZ_PASSPHRASE")
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	return passphrase, nil
+}
+
+// Decrypts an entry into a private temp file for the editor. The returned
+// cleanup removes the file and its directory
+func decryptToTemp(path string) (string, func(), error) {
+	data, err := readEntryFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Prefer the per-user runtime dir, it usually lives in memory
+	dir, err := os.MkdirTemp(os.Getenv("XDG_RUNTIME_DIR"), "journalz_ro-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	tempPath := filepath.Join(dir, filepath.Base(path))
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tempPath, cleanup, nil
+}
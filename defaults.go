@@ -0,0 +1,88 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The stock config and template ship inside the binary, so a plain
+// `go install` works without jz_ro-build next to the executable
+//
+//go:embed jz_ro-build/default.cfg jz_ro-build/entry_template.md
+var defaultFiles embed.FS
+
+const defaultTemplate = "entry_template.md"
+
+// $XDG_CONFIG_HOME/journal_zro/templates, or the old jz_ro-build directory
+// for installs that still keep their templates there
+func defaultTemplateDir() string {
+	dir := filepath.Join(configHome(), "templates")
+	if !fileExists(dir) && fileExists(filepath.Join(scriptDir, defaultTemplate)) {
+		return filepath.Clean(scriptDir)
+	}
+	return dir
+}
+
+func defaultFile(name string) []byte {
+	data, err := defaultFiles.ReadFile("jz_ro-build/" + name)
+	if err != nil {
+		// Only reachable if the embed list above is wrong
+		panic(err)
+	}
+	return data
+}
+
+// Reads a template, falling back to the built-in one when the default
+// template hasn't been written out with `init`
+func readTemplate(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && filepath.Base(path) == defaultTemplate {
+		return defaultFile(defaultTemplate), nil
+	}
+	return data, err
+}
+
+func templateExists(path string) bool {
+	return fileExists(path) || filepath.Base(path) == defaultTemplate
+}
+
+// Writes the built-in config and template to the config directory
+func initConfig(args []string) {
+	initCmd := flag.NewFlagSet("init", flag.ExitOnError)
+	force := initCmd.Bool("force", false, "Overwrite files that already exist")
+	local := initCmd.Bool("local", false, "Create a project journal (.journalz) in the current directory instead")
+	initCmd.Parse(args)
+
+	if *local {
+		initLocalJournal(*force)
+		return
+	}
+
+	templateDir := filepath.Join(configHome(), "templates")
+	files := []struct {
+		path string
+		data []byte
+	}{
+		{configPath, defaultFile("default.cfg")},
+		{filepath.Join(templateDir, defaultTemplate), defaultFile(defaultTemplate)},
+	}
+
+	for _, file := range files {
+		if fileExists(file.path) && !*force {
+			fmt.Println("Exists, skipping (use -force to overwrite):", file.path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(file.path), 0755); err != nil {
+			fmt.Println("Error creating directory:", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(file.path, file.data, 0644); err != nil {
+			fmt.Println("Error writing "+file.path+":", err)
+			os.Exit(1)
+		}
+		fmt.Println("Wrote", file.path)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type epubChapter struct {
+	Title string
+	Body  []string
+}
+type epubFile struct {
+	name    string
+	content string
+}
+type epubBook struct {
+	Title    string
+	Date     time.Time
+	Tags     []string
+	Chapters []epubChapter
+}
+
+// export epub [-out file] [-sections] <merge-name|tag>
+func exportEPUB(args []string) {
+	epubCmd := flag.NewFlagSet("export epub", flag.ExitOnError)
+	outPath := epubCmd.String("out", "", "File to write the book to (default: <name>.epub)")
+	sections := epubCmd.Bool("sections", false, "For merges, one chapter per heading in the merge instead of one per original")
+	withEncrypted := epubCmd.Bool("e", false, "Include encrypted entries (default: leave them out of the book)")
+	epubCmd.Parse(args)
+
+	if epubCmd.NArg() < 1 {
+		fmt.Println("Error: You must provide a merge name or a tag")
+		os.Exit(1)
+	}
+	name := strings.Join(epubCmd.Args(), " ")
+
+	var book epubBook
+	var err error
+	mergePath := filepath.Join(MERGE_DIR, name+".md")
+	if fileExists(mergePath) {
+		if isEncryptedFile(mergePath) && !*withEncrypted {
+			fmt.Println("Error: " + name + " is encrypted, pass -e to export it anyway")
+			os.Exit(1)
+		}
+		book, err = mergeBook(mergePath, *sections)
+	} else {
+		book, err = tagBook(name, *withEncrypted)
+	}
+	if err != nil {
+		fmt.Println("Error building book:", err)
+		os.Exit(1)
+	}
+	if len(book.Chapters) == 0 {
+		fmt.Println("No merge or entries found for " + name)
+		os.Exit(1)
+	}
+
+	if *outPath == "" {
+		*outPath = strings.ReplaceAll(name, "/", "_") + ".epub"
+	}
+	if err := writeEPUB(*outPath, book); err != nil {
+		fmt.Println("Error writing EPUB:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Wrote", len(book.Chapters), "chapters to", *outPath)
+}
+
+// A merge becomes one chapter per original, or per heading with -sections.
+// Originals that no longer exist fall back to the merge's own text
+func mergeBook(path string, bySection bool) (epubBook, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return epubBook{}, err
+	}
+	merge := Entry{Path: path, Info: info}
+	if merge.Tags, err = getLines(path, "Tags_", "_Tags"); err != nil {
+		return epubBook{}, err
+	}
+	if merge.MergeOriginals, err = getLines(path, "Originals_", "_Originals"); err != nil {
+		return epubBook{}, err
+	}
+	body, err := getLines(path, "Entry_", "_Entry")
+	if err != nil {
+		return epubBook{}, err
+	}
+
+	book := epubBook{Title: strings.TrimSuffix(info.Name(), ".md"), Tags: normalizeTags(merge.Tags)}
+	if book.Date, err = entryDate(merge); err != nil {
+		book.Date = info.ModTime()
+	}
+
+	if !bySection {
+		for _, original := range merge.MergeOriginals {
+			if strings.TrimSpace(original) == "" {
+				continue
+			}
+			originalPath, err := findEntryPath(strings.TrimSpace(original))
+			if err != nil {
+				continue
+			}
+			lines, err := getLines(originalPath, "Entry_", "_Entry")
+			if err != nil {
+				return epubBook{}, err
+			}
+			title := strings.TrimSuffix(original, ".md")
+			if date, err := getDate(originalPath); err == nil && date != "" {
+				title += " (" + strings.TrimSpace(date) + ")"
+			}
+			book.Chapters = append(book.Chapters, epubChapter{Title: title, Body: lines})
+		}
+		if len(book.Chapters) > 0 {
+			return book, nil
+		}
+	}
+
+	book.Chapters = splitSections(book.Title, body)
+	return book, nil
+}
+
+// Splits a body into chapters at its markdown headings
+func splitSections(title string, body []string) []epubChapter {
+	var chapters []epubChapter
+	current := epubChapter{Title: title}
+	for _, line := range body {
+		if match := headingRegex.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			if strings.TrimSpace(strings.Join(current.Body, "")) != "" {
+				chapters = append(chapters, current)
+			}
+			current = epubChapter{Title: match[2]}
+			continue
+		}
+		current.Body = append(current.Body, line)
+	}
+	if strings.TrimSpace(strings.Join(current.Body, "")) != "" || len(chapters) == 0 {
+		chapters = append(chapters, current)
+	}
+	return chapters
+}
+
+// A tag becomes one chapter per tagged entry, oldest first
+func tagBook(tag string, withEncrypted bool) (epubBook, error) {
+	entries, err := loadExportEntries()
+	if err != nil {
+		return epubBook{}, err
+	}
+	if !withEncrypted {
+		entries = withoutEncrypted(entries)
+	}
+	tag = normalizeTag(tag)
+	book := epubBook{Title: tag, Tags: []string{tag}}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if !matchesTags(entry.Tags, map[string]bool{tag: true}, false) {
+			continue
+		}
+		book.Chapters = append(book.Chapters, epubChapter{
+			Title: entry.Name + " (" + entry.Date.Format("01/02/2006") + ")",
+			Body:  entry.Body,
+		})
+		if entry.Date.After(book.Date) {
+			book.Date = entry.Date
+		}
+		for _, entryTag := range entry.Tags {
+			if !contains(book.Tags, entryTag) {
+				book.Tags = append(book.Tags, entryTag)
+			}
+		}
+	}
+	sort.Strings(book.Tags[1:])
+	return book, nil
+}
+func writeEPUB(path string, book epubBook) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+
+	// The mimetype must come first and be stored uncompressed
+	mimetype, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := mimetype.Write([]byte("application/epub+zip")); err != nil {
+		return err
+	}
+
+	files := []epubFile{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/style.css", epubStyle},
+		{"OEBPS/content.opf", epubPackage(book)},
+		{"OEBPS/nav.xhtml", epubNav(book)},
+	}
+	for i, chapter := range book.Chapters {
+		body := "<h1>" + html.EscapeString(chapter.Title) + "</h1>\n" + markdownToHTML(chapter.Body)
+		files = append(files, epubFile{"OEBPS/" + chapterFile(i), epubXHTML(chapter.Title, body)})
+	}
+
+	for _, f := range files {
+		w, err := archive.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+func chapterFile(i int) string {
+	return "chapter" + strconv.Itoa(i+1) + ".xhtml"
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const epubStyle = `body { font-family: serif; line-height: 1.5; }
+pre { white-space: pre-wrap; font-size: 0.9em; }
+`
+
+func epubPackage(book epubBook) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="en">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	b.WriteString(`    <dc:identifier id="book-id">urn:uuid:` + newUUID() + "</dc:identifier>\n")
+	b.WriteString("    <dc:title>" + html.EscapeString(book.Title) + "</dc:title>\n")
+	b.WriteString("    <dc:language>en</dc:language>\n")
+	b.WriteString("    <dc:creator>JournalZ-ro</dc:creator>\n")
+	b.WriteString("    <dc:date>" + book.Date.Format("2006-01-02") + "</dc:date>\n")
+	for _, tag := range book.Tags {
+		b.WriteString("    <dc:subject>" + html.EscapeString(tag) + "</dc:subject>\n")
+	}
+	b.WriteString(`    <meta property="dcterms:modified">` + time.Now().UTC().Format("2006-01-02T15:04:05Z") + "</meta>\n")
+	b.WriteString("  </metadata>\n  <manifest>\n")
+	b.WriteString(`    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	b.WriteString(`    <item id="style" href="style.css" media-type="text/css"/>` + "\n")
+	for i := range book.Chapters {
+		b.WriteString(`    <item id="chapter` + strconv.Itoa(i+1) + `" href="` + chapterFile(i) + `" media-type="application/xhtml+xml"/>` + "\n")
+	}
+	b.WriteString("  </manifest>\n  <spine>\n")
+	for i := range book.Chapters {
+		b.WriteString(`    <itemref idref="chapter` + strconv.Itoa(i+1) + `"/>` + "\n")
+	}
+	b.WriteString("  </spine>\n</package>\n")
+	return b.String()
+}
+func epubNav(book epubBook) string {
+	var b strings.Builder
+	b.WriteString(`<nav epub:type="toc" id="toc"><h1>Contents</h1>` + "\n<ol>\n")
+	for i, chapter := range book.Chapters {
+		b.WriteString(`<li><a href="` + chapterFile(i) + `">` + html.EscapeString(chapter.Title) + "</a></li>\n")
+	}
+	b.WriteString("</ol>\n</nav>\n")
+	return epubXHTML(book.Title, b.String())
+}
+func epubXHTML(title string, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="en" lang="en">
+<head>
+<meta charset="utf-8"/>
+<title>` + html.EscapeString(title) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+` + body + `</body>
+</html>
+`
+}
+
+// Random (version 4) UUID for the book identifier
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An entry or merge with everything the exporters need already read
+type exportEntry struct {
+	Entry
+	Name       string
+	Date       time.Time
+	Body       []string
+	Tags       []string
+	IsMerge    bool
+	MergedInto []string
+}
+
+// Reads every entry and merge, newest first
+func loadExportEntries() ([]exportEntry, error) {
+	entries, err := loadEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var exported []exportEntry
+	for _, entry := range entries {
+		body, err := getLines(entry.Path, "Entry_", "_Entry")
+		if err != nil {
+			return nil, err
+		}
+		date, err := entryDate(entry)
+		if err != nil {
+			date = entry.Info.ModTime()
+		}
+		exported = append(exported, exportEntry{
+			Entry:   entry,
+			Name:    strings.TrimSuffix(entry.Info.Name(), ".md"),
+			Date:    date,
+			Body:    body,
+			Tags:    normalizeTags(entry.Tags),
+			IsMerge: entry.MergeOriginals != nil,
+		})
+	}
+
+	sort.SliceStable(exported, func(i, j int) bool {
+		if exported[i].Date.Equal(exported[j].Date) {
+			return exported[i].Name < exported[j].Name
+		}
+		return exported[i].Date.After(exported[j].Date)
+	})
+	return exported, nil
+}
+
+// Encrypted entries stay out of exports unless asked for, the output is plaintext
+func withoutEncrypted(entries []exportEntry) []exportEntry {
+	var plain []exportEntry
+	for _, entry := range entries {
+		if !isEncryptedFile(entry.Path) {
+			plain = append(plain, entry)
+		}
+	}
+	return plain
+}
+
+// Every tag in use, including the parents of hierarchical tags
+func exportTags(entries []exportEntry) []string {
+	set := make(map[string]bool)
+	for _, entry := range entries {
+		for _, tag := range entry.Tags {
+			for _, name := range tagAncestors(tag) {
+				set[name] = true
+			}
+		}
+	}
+	var tags []string
+	for tag := range set {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+func exportJournal(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: journalz_ro export html -out <dir> | epub [-out <file>] <merge-name|tag>")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "html":
+		exportHTML(args[1:])
+	case "epub":
+		exportEPUB(args[1:])
+	default:
+		fmt.Println("Unknown export format: " + args[0] + ". Use 'html' or 'epub'.")
+		os.Exit(1)
+	}
+}
+
+var htmlFuncs = template.FuncMap{
+	"entryPage": entryPage,
+	"tagPage":   tagPage,
+	"date": func(t time.Time) string {
+		return t.Format("01/02/2006")
+	},
+}
+
+const htmlLayout = `{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8"/>
+<meta name="viewport" content="width=device-width, initial-scale=1"/>
+<title>{{.}} - JournalZ-ro</title>
+<style>
+body { font-family: sans-serif; max-width: 46rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+nav a { margin-right: 1rem; }
+.meta { color: #666; font-size: 0.9rem; }
+.tag { display: inline-block; background: #eef; border-radius: 0.3rem; padding: 0 0.4rem; margin-right: 0.3rem; text-decoration: none; }
+pre { background: #f4f4f4; padding: 0.5rem; overflow-x: auto; }
+li { margin-bottom: 0.3rem; }
+</style>
+</head>
+<body>
+<nav><a href="index.html">Chronological</a><a href="tags.html">Tags</a></nav>
+<h1>{{.}}</h1>
+{{end}}
+{{define "footer"}}</body>
+</html>
+{{end}}
+{{define "tags"}}{{range .}}<a class="tag" href="{{tagPage .}}">{{.}}</a>{{end}}{{end}}
+{{define "list"}}<ul>
+{{range .}}<li><a href="{{entryPage .Name .IsMerge}}">{{.Name}}</a>{{if .IsMerge}} (merge){{end}} <span class="meta">{{date .Date}}</span> {{template "tags" .Tags}}</li>
+{{end}}</ul>
+{{end}}
+{{define "entry"}}{{template "header" .Entry.Name}}<p class="meta">{{if .Entry.IsMerge}}Merge{{else}}Entry{{end}} written {{date .Entry.Date}}</p>
+<p>{{template "tags" .Entry.Tags}}</p>
+{{.Body}}
+{{if .Originals}}<h2>Originals</h2>
+<ul>
+{{range .Originals}}<li>{{if .Page}}<a href="{{.Page}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</li>
+{{end}}</ul>
+{{end}}{{if .Entry.MergedInto}}<h2>Merged into</h2>
+<ul>
+{{range .Entry.MergedInto}}<li><a href="{{entryPage . true}}">{{.}}</a></li>
+{{end}}</ul>
+{{end}}{{template "footer"}}{{end}}
+{{define "index"}}{{template "header" "Journal"}}{{template "list" .}}{{template "footer"}}{{end}}
+{{define "tag"}}{{template "header" .Tag}}{{template "list" .Entries}}{{template "footer"}}{{end}}
+{{define "tagIndex"}}{{template "header" "Tags"}}<ul>
+{{range .}}<li><a href="{{tagPage .Tag}}">{{.Tag}}</a> ({{.Count}})</li>
+{{end}}</ul>
+{{template "footer"}}{{end}}`
+
+// Pages are kept flat in the output directory so every link is relative to it
+func entryFile(name string, isMerge bool) string {
+	name = strings.TrimSuffix(name, ".md")
+	if isMerge {
+		return "merge-" + name + ".html"
+	}
+	return "entry-" + name + ".html"
+}
+func tagFile(tag string) string {
+	return "tag-" + strings.ReplaceAll(tag, "/", "__") + ".html"
+}
+
+// Links to the files above
+func entryPage(name string, isMerge bool) string {
+	return url.PathEscape(entryFile(name, isMerge))
+}
+func tagPage(tag string) string {
+	return url.PathEscape(tagFile(tag))
+}
+
+// An Originals_ line with the page it links to, if any
+type originalLink struct {
+	Name string
+	Page string
+}
+
+// Resolves a merge's originals against the exported entries the way
+// findEntryPath does, an entry before a merge of the same name. Originals
+// that weren't exported (encrypted ones without -e) get no page
+func originalLinks(originals []string, entries []exportEntry) []originalLink {
+	var links []originalLink
+	for _, original := range originals {
+		name := strings.TrimSuffix(strings.TrimSpace(original), ".md")
+		link := originalLink{Name: original}
+		for _, entry := range entries {
+			if entry.Name == name && (link.Page == "" || !entry.IsMerge) {
+				link.Page = entryPage(entry.Name, entry.IsMerge)
+			}
+		}
+		links = append(links, link)
+	}
+	return links
+}
+func exportHTML(args []string) {
+	htmlCmd := flag.NewFlagSet("export html", flag.ExitOnError)
+	outDir := htmlCmd.String("out", "", "Directory to write the site to")
+	withEncrypted := htmlCmd.Bool("e", false, "Include encrypted entries (default: leave them out of the site)")
+	htmlCmd.Parse(args)
+
+	if *outDir == "" {
+		fmt.Println("Error: You must provide an output directory with -out")
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fmt.Println("Error creating output directory", err)
+		os.Exit(1)
+	}
+
+	entries, err := loadExportEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+	if !*withEncrypted {
+		entries = withoutEncrypted(entries)
+	}
+
+	// Link originals back to the merges they ended up in
+	mergedInto := make(map[string][]string)
+	for _, entry := range entries {
+		for _, original := range entry.MergeOriginals {
+			mergedInto[original] = append(mergedInto[original], entry.Name)
+		}
+	}
+	for i := range entries {
+		if !entries[i].IsMerge {
+			entries[i].MergedInto = mergedInto[entries[i].Info.Name()]
+		}
+	}
+
+	pages := template.Must(template.New("site").Funcs(htmlFuncs).Parse(htmlLayout))
+	write := func(file string, name string, data any) {
+		out, err := os.Create(filepath.Join(*outDir, file))
+		if err != nil {
+			fmt.Println("Error creating "+file, err)
+			os.Exit(1)
+		}
+		defer out.Close()
+		if err := pages.ExecuteTemplate(out, name, data); err != nil {
+			fmt.Println("Error writing "+file, err)
+			os.Exit(1)
+		}
+	}
+
+	for _, entry := range entries {
+		write(entryFile(entry.Name, entry.IsMerge), "entry", map[string]any{
+			"Entry":     entry,
+			"Originals": originalLinks(entry.MergeOriginals, entries),
+			"Body":      template.HTML(markdownToHTML(entry.Body)),
+		})
+	}
+	write("index.html", "index", entries)
+
+	type tagCount struct {
+		Tag   string
+		Count int
+	}
+	var tagCounts []tagCount
+	for _, tag := range exportTags(entries) {
+		var tagged []exportEntry
+		for _, entry := range entries {
+			for _, entryTag := range entry.Tags {
+				if tagMatches(entryTag, tag) {
+					tagged = append(tagged, entry)
+					break
+				}
+			}
+		}
+		write(tagFile(tag), "tag", map[string]any{"Tag": tag, "Entries": tagged})
+		tagCounts = append(tagCounts, tagCount{Tag: tag, Count: len(tagged)})
+	}
+	write("tags.html", "tagIndex", tagCounts)
+
+	fmt.Println("Exported", len(entries), "entries and", len(tagCounts), "tags to", *outDir)
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestOriginalLinks(t *testing.T) {
+	exported := []exportEntry{
+		{Name: "Entry1"},
+		{Name: "notes", IsMerge: true},
+		{Name: "Entry3", IsMerge: true},
+		{Name: "Entry3"},
+	}
+	got := originalLinks([]string{"Entry1.md", "notes.md", "Entry3.md", "Entry4.md"}, exported)
+	want := []originalLink{
+		{Name: "Entry1.md", Page: "entry-Entry1.html"},
+		{Name: "notes.md", Page: "merge-notes.html"},
+		{Name: "Entry3.md", Page: "entry-Entry3.html"},
+		// Not exported, e.g. encrypted
+		{Name: "Entry4.md"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("originalLinks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("originalLinks[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Bookkeeping kept next to the entries that changes on its own (views,
+// review schedule, import record) and doesn't belong in entry commits
+var untrackedStateFiles = []string{viewsFile, reviewFile, importedFile}
+
+// Auto-commit is opt-in via GIT_AUTOCOMMIT=true in the config
+func gitEnabled() bool {
+	return cfg.GitAutoCommit
+}
+func runGit(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", SAVEDIR}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// Initialises a repository in SAVEDIR if there isn't one yet, ignoring the
+// state files unless there is a .gitignore already
+func gitInit() error {
+	if fileExists(filepath.Join(SAVEDIR, ".git")) {
+		return nil
+	}
+	if _, err := runGit("init", "-q"); err != nil {
+		return err
+	}
+	ignorePath := filepath.Join(SAVEDIR, ".gitignore")
+	if fileExists(ignorePath) {
+		return nil
+	}
+	var ignore strings.Builder
+	for _, name := range untrackedStateFiles {
+		ignore.WriteString("/" + name + "\n")
+	}
+	return os.WriteFile(ignorePath, []byte(ignore.String()), 0644)
+}
+
+// Commits everything that changed in SAVEDIR but the state files. Does
+// nothing when git integration is off or there is nothing to commit
+func gitCommit(message string) {
+	if !gitEnabled() {
+		return
+	}
+	if err := gitInit(); err != nil {
+		fmt.Println("Error initialising git repository", err)
+		return
+	}
+	if _, err := runGit("add", "-A"); err != nil {
+		fmt.Println("Error staging journal changes", err)
+		return
+	}
+	// Repositories made before the .gitignore may track them already
+	if _, err := runGit(append([]string{"reset", "-q", "--"}, untrackedStateFiles...)...); err != nil {
+		fmt.Println("Error staging journal changes", err)
+		return
+	}
+	staged, err := runGit("diff", "--cached", "--name-only")
+	if err != nil {
+		fmt.Println("Error reading git status", err)
+		return
+	}
+	if strings.TrimSpace(staged) == "" {
+		return
+	}
+	if _, err := runGit("commit", "-q", "-m", message); err != nil {
+		fmt.Println("Error committing journal changes", err)
+	}
+}
+
+// e.g. "Add Entry4.md [tags: go, work/projectx]"
+func describeEntry(action string, name string, tags []string) string {
+	message := action + " " + name
+	if tags = normalizeTags(tags); len(tags) > 0 {
+		message += " [tags: " + strings.Join(tags, ", ") + "]"
+	}
+	return message
+}
+
+// Resolves "Entry4", "Entry4.md" or a merge name to a file in the journal
+func findEntryPath(name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid entry name %s", name)
+	}
+	if !strings.HasSuffix(name, ".md") {
+		name += ".md"
+	}
+	for _, dir := range []string{SAVEDIR, MERGE_DIR} {
+		path := filepath.Join(dir, name)
+		if fileExists(path) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no entry or merge named %s", strings.TrimSuffix(name, ".md"))
+}
+func showLog(args []string) {
+	logCmd := flag.NewFlagSet("log", flag.ExitOnError)
+	patch := logCmd.Bool("p", false, "Show the changes made in each commit")
+	logCmd.Parse(args)
+
+	if logCmd.NArg() != 1 {
+		fmt.Println("Error: You must provide exactly one entry name")
+		os.Exit(1)
+	}
+	if !fileExists(filepath.Join(SAVEDIR, ".git")) {
+		fmt.Println("No history yet. Set GIT_AUTOCOMMIT=true in your config to start versioning the journal.")
+		os.Exit(1)
+	}
+
+	path, err := findEntryPath(logCmd.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	gitArgs := []string{"-C", SAVEDIR, "log", "--follow", "--date=short", "--format=%C(yellow)%h%Creset %ad %s"}
+	if *patch {
+		gitArgs = append(gitArgs, "-p")
+	}
+	gitArgs = append(gitArgs, "--", path)
+
+	cmd := exec.Command("git", gitArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Println("Error reading history", err)
+		os.Exit(1)
+	}
+}
@@ -1,3 +1,10 @@
 module github.com/projectz-ro/journalz_ro
 
 go 1.23.1
+
+require (
+	golang.org/x/crypto v0.36.0
+	golang.org/x/term v0.30.0
+)
+
+require golang.org/x/sys v0.31.0 // indirect
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type graphNode struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	Date  string `json:"date,omitempty"`
+}
+
+// kind is tagged (entry to tag), merges (merge to original), links (entry
+// to entry) or cooccurs (tag to tag, weight = entries tagged with both)
+type graphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+type journalGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+func entryNodeID(entry exportEntry) string {
+	if entry.IsMerge {
+		return "merge:" + entry.Name
+	}
+	return "entry:" + entry.Name
+}
+
+// Entries, merges and tags as nodes. With a tag, only the entries carrying
+// it (or a tag below it), plus the entries and merges one link or merge away
+func buildGraph(entries []exportEntry, tag string) journalGraph {
+	selected := make(map[string]bool)
+	for _, entry := range entries {
+		if tag == "" || matchesTags(entry.Tags, map[string]bool{tag: true}, true) {
+			selected[entryNodeID(entry)] = true
+		}
+	}
+
+	var graph journalGraph
+	ids := make(map[string]bool)
+	addNode := func(node graphNode) {
+		if !ids[node.ID] {
+			ids[node.ID] = true
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+	addEntryNode := func(entry exportEntry) {
+		kind := "entry"
+		if entry.IsMerge {
+			kind = "merge"
+		}
+		addNode(graphNode{ID: entryNodeID(entry), Kind: kind, Label: entry.Name, Date: entry.Date.Format("01/02/2006")})
+	}
+
+	// Link and Originals_ names resolve like findEntryPath: entries first.
+	// Every entry counts, links may lead out of the tag
+	byName := make(map[string]exportEntry)
+	for _, entry := range entries {
+		if !entry.IsMerge {
+			byName[entry.Name] = entry
+		}
+	}
+	for _, entry := range entries {
+		if _, taken := byName[entry.Name]; !taken {
+			byName[entry.Name] = entry
+		}
+	}
+
+	cooccurs := make(map[[2]string]int)
+	for _, entry := range entries {
+		id := entryNodeID(entry)
+		if selected[id] {
+			addEntryNode(entry)
+			tags := uniqueTags(entry.Tags)
+			sort.Strings(tags)
+			for i, name := range tags {
+				addNode(graphNode{ID: "tag:" + name, Kind: "tag", Label: name})
+				graph.Edges = append(graph.Edges, graphEdge{Source: id, Target: "tag:" + name, Kind: "tagged"})
+				for _, other := range tags[i+1:] {
+					cooccurs[[2]string{name, other}]++
+				}
+			}
+		}
+
+		// Edges touching a selected entry, bringing in the other end
+		addEdge := func(target exportEntry, kind string) {
+			targetID := entryNodeID(target)
+			if targetID == id || (!selected[id] && !selected[targetID]) {
+				return
+			}
+			addEntryNode(entry)
+			addEntryNode(target)
+			graph.Edges = append(graph.Edges, graphEdge{Source: id, Target: targetID, Kind: kind})
+		}
+		for _, original := range entry.MergeOriginals {
+			if target, ok := byName[strings.TrimSuffix(strings.TrimSpace(original), ".md")]; ok {
+				addEdge(target, "merges")
+			}
+		}
+		for _, link := range parseLinks(entry.Body) {
+			if target, ok := byName[link]; ok {
+				addEdge(target, "links")
+			}
+		}
+	}
+	for pair, count := range cooccurs {
+		graph.Edges = append(graph.Edges, graphEdge{Source: "tag:" + pair[0], Target: "tag:" + pair[1], Kind: "cooccurs", Weight: count})
+	}
+
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Target < b.Target
+	})
+	return graph
+}
+
+func writeDOT(w io.Writer, graph journalGraph) {
+	shapes := map[string]string{"entry": "box", "merge": "box3d", "tag": "ellipse"}
+	fmt.Fprintln(w, "digraph journal {")
+	fmt.Fprintln(w, "  node [fontname=\"sans-serif\"];")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(w, "  %s [label=%s, shape=%s];\n", strconv.Quote(node.ID), strconv.Quote(node.Label), shapes[node.Kind])
+	}
+	for _, edge := range graph.Edges {
+		attrs := "kind=" + edge.Kind
+		switch edge.Kind {
+		case "tagged":
+			attrs += ", style=dotted"
+		case "merges":
+			attrs += ", style=bold"
+		case "cooccurs":
+			attrs += ", dir=none, penwidth=" + strconv.Itoa(edge.Weight) + ", label=" + strconv.Itoa(edge.Weight)
+		}
+		fmt.Fprintf(w, "  %s -> %s [%s];\n", strconv.Quote(edge.Source), strconv.Quote(edge.Target), attrs)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+func writeGraphML(w io.Writer, graph journalGraph) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "kind", For: "node", Name: "kind", Type: "string"},
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "date", For: "node", Name: "date", Type: "string"},
+			{ID: "edgekind", For: "edge", Name: "kind", Type: "string"},
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+		},
+	}
+	doc.Graph.EdgeDefault = "directed"
+	for _, node := range graph.Nodes {
+		data := []graphMLData{{"kind", node.Kind}, {"label", node.Label}}
+		if node.Date != "" {
+			data = append(data, graphMLData{"date", node.Date})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: node.ID, Data: data})
+	}
+	for _, edge := range graph.Edges {
+		data := []graphMLData{{"edgekind", edge.Kind}}
+		if edge.Weight > 0 {
+			data = append(data, graphMLData{"weight", strconv.Itoa(edge.Weight)})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: edge.Source, Target: edge.Target, Data: data})
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, out)
+	return err
+}
+
+func exportGraph(args []string) {
+	graphCmd := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := graphCmd.String("format", "dot", "Output format: dot, json or graphml")
+	outPath := graphCmd.String("out", "", "File to write the graph to (default: standard output)")
+	tag := graphCmd.String("tag", "", "Only the entries with this tag and what they connect to")
+	withEncrypted := graphCmd.Bool("e", false, "Include encrypted entries (default: leave them out of the graph)")
+	graphCmd.Parse(args)
+
+	if *format != "dot" && *format != "json" && *format != "graphml" {
+		fmt.Println("Unknown format: " + *format + ". Use dot, json or graphml.")
+		os.Exit(1)
+	}
+
+	entries, err := loadExportEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+	if !*withEncrypted {
+		entries = withoutEncrypted(entries)
+	}
+	graph := buildGraph(entries, normalizeTag(*tag))
+	if *tag != "" && len(graph.Nodes) == 0 {
+		fmt.Println("No entries tagged " + *tag)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Println("Error creating output file:", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch *format {
+	case "dot":
+		writeDOT(w, graph)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(graph)
+	case "graphml":
+		err = writeGraphML(w, graph)
+	}
+	if err != nil {
+		fmt.Println("Error writing graph:", err)
+		os.Exit(1)
+	}
+}
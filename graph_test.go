@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestBuildGraphTagKeepsNeighbours(t *testing.T) {
+	entries := []exportEntry{
+		{Name: "Entry1", Tags: []string{"golang"}, Body: []string{"see [[Entry2]]"}},
+		{Name: "Entry2", Tags: []string{"reading"}, Body: []string{"back to [[Entry1]] and [[Entry3]]"}},
+		{Name: "Entry3", Tags: []string{"travel"}},
+		{Name: "notes", IsMerge: true, Entry: Entry{MergeOriginals: []string{"Entry1.md", "Entry3.md"}}},
+	}
+	graph := buildGraph(entries, "golang")
+
+	nodes := make(map[string]bool)
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = true
+	}
+	for _, id := range []string{"entry:Entry1", "entry:Entry2", "merge:notes", "tag:golang"} {
+		if !nodes[id] {
+			t.Errorf("missing node %s, got %v", id, graph.Nodes)
+		}
+	}
+	// Two hops away, and the neighbours' own tags stay out
+	for _, id := range []string{"entry:Entry3", "tag:reading", "tag:travel"} {
+		if nodes[id] {
+			t.Errorf("unexpected node %s", id)
+		}
+	}
+
+	edges := make(map[graphEdge]bool)
+	for _, edge := range graph.Edges {
+		edges[edge] = true
+	}
+	for _, edge := range []graphEdge{
+		{Source: "entry:Entry1", Target: "entry:Entry2", Kind: "links"},
+		{Source: "entry:Entry2", Target: "entry:Entry1", Kind: "links"},
+		{Source: "merge:notes", Target: "entry:Entry1", Kind: "merges"},
+	} {
+		if !edges[edge] {
+			t.Errorf("missing edge %v, got %v", edge, graph.Edges)
+		}
+	}
+	if len(graph.Edges) != 4 {
+		t.Errorf("got %d edges, want 4 (3 above and Entry1 tagged golang): %v", len(graph.Edges), graph.Edges)
+	}
+}
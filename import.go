@@ -0,0 +1,482 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const importedFile = ".imported.json"
+
+// One journal item from another tool, before it becomes an entry
+type importItem struct {
+	// Stable id in the source (uuid, path, date+title) for duplicate detection
+	SourceID string
+	Date     time.Time
+	Body     []string
+	Tags     []string
+}
+
+var (
+	jrnlHeaderRegex  = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{1,2}:\d{2}(?::\d{2})?(?: ?[AaPp][Mm])?)\] ?(.*)$`)
+	jrnlTagRegex     = regexp.MustCompile(`(?:^|\s)@([\p{L}\d_/-]+)`)
+	hashTagRegex     = regexp.MustCompile(`(?:^|\s)#([\p{L}\d_/-]*[\p{L}_/-][\p{L}\d_/-]*)`)
+	dayOneEscapes    = regexp.MustCompile(`\\([\\.\-!#()\[\]*_+>~])`)
+	frontmatterList  = regexp.MustCompile(`^\s*-\s+(.+)$`)
+	frontmatterField = regexp.MustCompile(`^([A-Za-z_]+):\s*(.*)$`)
+)
+
+// Source ids already imported, mapped to the entry they became
+func loadImported() (map[string]string, error) {
+	imported := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(SAVEDIR, importedFile))
+	if os.IsNotExist(err) {
+		return imported, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return nil, fmt.Errorf("invalid import record: %w", err)
+	}
+	return imported, nil
+}
+func saveImported(imported map[string]string) error {
+	data, err := json.MarshalIndent(imported, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(SAVEDIR, importedFile), data, 0644)
+}
+func importJournal(args []string) {
+	importCmd := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := importCmd.Bool("dry-run", false, "Report what would be imported without writing anything")
+	importCmd.Parse(args)
+
+	if importCmd.NArg() != 2 {
+		fmt.Println("Usage: journalz_ro import [-dry-run] jrnl|dayone|obsidian <path>")
+		os.Exit(1)
+	}
+	format, source := importCmd.Arg(0), importCmd.Arg(1)
+
+	var items []importItem
+	var err error
+	switch format {
+	case "jrnl":
+		items, err = readJrnl(source)
+	case "dayone":
+		items, err = readDayOne(source)
+	case "obsidian":
+		items, err = readObsidian(source)
+	default:
+		fmt.Println("Unknown import format: " + format + ". Use 'jrnl', 'dayone' or 'obsidian'.")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Println("Error reading "+source+":", err)
+		os.Exit(1)
+	}
+
+	template, err := readTemplate(TEMPLATE)
+	if err != nil {
+		fmt.Println("Error reading template file:", err)
+		os.Exit(1)
+	}
+	imported, err := loadImported()
+	if err != nil {
+		fmt.Println("Error reading import record:", err)
+		os.Exit(1)
+	}
+
+	// Oldest first so entry numbers follow the dates
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Date.Before(items[j].Date)
+	})
+
+	entryNumber, err := countEntries()
+	if err != nil {
+		fmt.Println("Error counting entries", err)
+		os.Exit(1)
+	}
+	added, skipped := 0, 0
+	var names []string
+	for _, item := range items {
+		key := format + ":" + item.SourceID
+		if name, ok := imported[key]; ok {
+			fmt.Println(theme.Warning+"skip", theme.Reset, item.SourceID, "(already imported as "+name+")")
+			skipped++
+			continue
+		}
+
+		// Numbered by hand so a dry run reports the same names
+		for fileExists(filepath.Join(SAVEDIR, "Entry"+strconv.Itoa(entryNumber)+".md")) {
+			entryNumber++
+		}
+		name := "Entry" + strconv.Itoa(entryNumber) + ".md"
+		entryNumber++
+		fmt.Println(theme.Success+"add ", theme.Reset, item.Date.Format("01/02/2006"), item.SourceID, "->", name, "["+strings.Join(item.Tags, ", ")+"]")
+		added++
+		if *dryRun {
+			continue
+		}
+
+		content, err := fillTemplate(template, item)
+		if err != nil {
+			fmt.Println("Error filling template for "+item.SourceID, err)
+			os.Exit(1)
+		}
+		if err := writeEntryFile(filepath.Join(SAVEDIR, name), content, shouldEncrypt(item.Tags)); err != nil {
+			fmt.Println("Error writing "+name, err)
+			os.Exit(1)
+		}
+		imported[key] = name
+		names = append(names, name)
+		if err := saveImported(imported); err != nil {
+			fmt.Println("Error saving import record", err)
+			os.Exit(1)
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run:", added, "entries would be imported,", skipped, "already imported")
+		return
+	}
+	fmt.Println("Imported", added, "entries,", skipped, "already imported")
+	if added > 0 {
+		gitCommit("Import " + strconv.Itoa(added) + " entries from " + format + "\n\n" + strings.Join(names, ", "))
+	}
+}
+
+// Puts an item into the entry template: date header, Entry_ body and Tags_
+func fillTemplate(template []byte, item importItem) ([]byte, error) {
+	content := []byte(strings.ReplaceAll(string(template), "MM/DD/YYYY", item.Date.Format("01/02/2006")))
+	content, err := replaceSection(content, "Entry_", "_Entry", item.Body)
+	if err != nil {
+		return nil, err
+	}
+	return replaceSection(content, "Tags_", "_Tags", item.Tags)
+}
+
+// Trims blank lines from both ends of a body
+func trimBody(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+func uniqueTags(tags []string) []string {
+	var unique []string
+	for _, tag := range tags {
+		tag = cleanTag(strings.TrimLeft(tag, "@#"))
+		if tag != "" && !contains(unique, tag) {
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+// jrnl plain text ("[2024-01-02 09:30] Title. Body") or its JSON export
+func readJrnl(path string) ([]importItem, error) {
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return readJrnlJSON(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var items []importItem
+	var current *importItem
+	finish := func() {
+		if current != nil {
+			current.Body = trimBody(current.Body)
+			current.Tags = uniqueTags(jrnlTags(current.Body))
+			items = append(items, *current)
+		}
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := jrnlHeaderRegex.FindStringSubmatch(line)
+		if match == nil {
+			if current != nil {
+				current.Body = append(current.Body, line)
+			}
+			continue
+		}
+
+		finish()
+		date, err := parseJrnlDate(match[1])
+		if err != nil {
+			return nil, err
+		}
+		current = &importItem{SourceID: match[1] + " " + match[2], Date: date, Body: []string{match[2]}}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+	return items, nil
+}
+func parseJrnlDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02 15:04:05", "2006-01-02 03:04 PM", "2006-01-02 03:04PM", "2006-01-02 3:04 PM"} {
+		if date, err := time.ParseInLocation(layout, strings.ToUpper(value), time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised jrnl date %q", value)
+}
+func jrnlTags(lines []string) []string {
+	var tags []string
+	for _, line := range lines {
+		for _, match := range jrnlTagRegex.FindAllStringSubmatch(line, -1) {
+			tags = append(tags, match[1])
+		}
+	}
+	return tags
+}
+func readJrnlJSON(path string) ([]importItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var export struct {
+		Entries []struct {
+			Date  string   `json:"date"`
+			Time  string   `json:"time"`
+			Title string   `json:"title"`
+			Body  string   `json:"body"`
+			Tags  []string `json:"tags"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	var items []importItem
+	for _, entry := range export.Entries {
+		date, err := parseJrnlDate(entry.Date + " " + entry.Time)
+		if err != nil {
+			return nil, err
+		}
+		body := append([]string{entry.Title}, strings.Split(entry.Body, "\n")...)
+		items = append(items, importItem{
+			SourceID: entry.Date + " " + entry.Time + " " + entry.Title,
+			Date:     date,
+			Body:     trimBody(body),
+			Tags:     uniqueTags(entry.Tags),
+		})
+	}
+	return items, nil
+}
+
+// Day One JSON export: the .zip, a folder of journal .json files, or one .json file
+func readDayOne(path string) ([]importItem, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []importItem
+	switch {
+	case info.IsDir():
+		files, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			journal, err := parseDayOne(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+			}
+			items = append(items, journal...)
+		}
+	case strings.HasSuffix(strings.ToLower(path), ".zip"):
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer archive.Close()
+		for _, file := range archive.File {
+			if !strings.HasSuffix(file.Name, ".json") {
+				continue
+			}
+			reader, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return nil, err
+			}
+			journal, err := parseDayOne(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name, err)
+			}
+			items = append(items, journal...)
+		}
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		items, err = parseDayOne(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+func parseDayOne(data []byte) ([]importItem, error) {
+	var export struct {
+		Entries []struct {
+			UUID         string   `json:"uuid"`
+			CreationDate string   `json:"creationDate"`
+			Text         string   `json:"text"`
+			Tags         []string `json:"tags"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	var items []importItem
+	for _, entry := range export.Entries {
+		date, err := time.Parse(time.RFC3339, entry.CreationDate)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %w", entry.UUID, err)
+		}
+		// Day One backslash-escapes markdown punctuation in its export
+		text := dayOneEscapes.ReplaceAllString(entry.Text, "$1")
+		items = append(items, importItem{
+			SourceID: entry.UUID,
+			Date:     date.Local(),
+			Body:     trimBody(strings.Split(text, "\n")),
+			Tags:     uniqueTags(entry.Tags),
+		})
+	}
+	return items, nil
+}
+
+// Every note in an Obsidian vault. Tags come from the frontmatter and inline
+// #tags, the date from a date/created field or else the file's mtime
+func readObsidian(vault string) ([]importItem, error) {
+	var items []importItem
+	err := filepath.Walk(vault, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != vault && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(info.Name(), ".md") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(vault, path)
+		if err != nil {
+			return err
+		}
+
+		fields, tags, body := splitFrontmatter(strings.Split(string(data), "\n"))
+		date := info.ModTime()
+		for _, key := range []string{"date", "created"} {
+			if value := strings.Trim(fields[key], `"' `); value != "" {
+				if parsed, err := time.ParseInLocation("2006-01-02", value[:min(len(value), 10)], time.Local); err == nil {
+					date = parsed
+					break
+				}
+			}
+		}
+
+		inCode := false
+		for _, line := range body {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inCode = !inCode
+			}
+			if inCode {
+				continue
+			}
+			for _, match := range hashTagRegex.FindAllStringSubmatch(line, -1) {
+				tags = append(tags, match[1])
+			}
+		}
+
+		title := "# " + strings.TrimSuffix(info.Name(), ".md")
+		items = append(items, importItem{
+			SourceID: filepath.ToSlash(rel),
+			Date:     date,
+			Body:     append([]string{title, ""}, trimBody(body)...),
+			Tags:     uniqueTags(tags),
+		})
+		return nil
+	})
+	return items, err
+}
+
+// Splits YAML frontmatter off a note. Only what's needed here is understood:
+// scalar fields and the tags field as a list, flow list or comma list
+func splitFrontmatter(lines []string) (map[string]string, []string, []string) {
+	fields := make(map[string]string)
+	var tags []string
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return fields, tags, lines
+	}
+
+	listKey := ""
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "---" {
+			return fields, tags, lines[i+1:]
+		}
+		if match := frontmatterList.FindStringSubmatch(line); match != nil && listKey != "" {
+			if listKey == "tags" || listKey == "tag" {
+				tags = append(tags, strings.Trim(match[1], `"' `))
+			}
+			continue
+		}
+		match := frontmatterField.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		key, value := strings.ToLower(match[1]), strings.TrimSpace(match[2])
+		listKey = key
+		fields[key] = value
+		if (key == "tags" || key == "tag") && value != "" {
+			for _, tag := range strings.Split(strings.Trim(value, "[]"), ",") {
+				tags = append(tags, strings.Trim(tag, `"' `))
+			}
+		}
+	}
+
+	// No closing marker, so it wasn't frontmatter after all
+	return make(map[string]string), nil, lines
+}
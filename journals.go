@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The journal picked with -j or JOURNALZ_JOURNAL, empty for DEFAULT_JOURNAL
+var journalName string = os.Getenv("JOURNALZ_JOURNAL")
+
+// Pulls a leading -j name (or -j=name) off the arguments
+func takeJournalFlag(args []string) (string, []string, bool) {
+	if len(args) >= 2 && (args[0] == "-j" || args[0] == "--j") {
+		return args[1], args[2:], true
+	}
+	if len(args) >= 1 && (strings.HasPrefix(args[0], "-j=") || strings.HasPrefix(args[0], "--j=")) {
+		return args[0][strings.Index(args[0], "=")+1:], args[1:], true
+	}
+	return "", args, false
+}
+
+// -j and -color, in any order, before the subcommand
+func takeGlobalFlags(args []string) []string {
+	for {
+		if name, rest, ok := takeJournalFlag(args); ok {
+			journalName = name
+			args = rest
+		} else if mode, rest, ok := takeColorFlag(args); ok {
+			colorMode = mode
+			args = rest
+		} else {
+			return args
+		}
+	}
+}
+
+// Names of the journals in the config file, for completion
+func journalNames() []string {
+	file, err := readConfigFile(configPath)
+	if err != nil {
+		return nil
+	}
+	return file.journalNames()
+}
+
+// A project-local journal is a .journalz directory (the journal itself, with
+// an optional config.cfg inside) or a .journalz config file, in the working
+// directory or the closest parent that has one, the way git finds .git
+func findLocalJournal() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, ".journalz")
+		if fileExists(path) {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// A local journal takes the main config's top-level settings and overrides
+// them with its own. Paths and git stay with the project: relative paths are
+// taken from the project directory and GIT_AUTOCOMMIT is only on if the local
+// config says so, the project usually being a repository already
+func buildLocalConfig(global configFile, path string) (Config, error) {
+	project := filepath.Dir(path)
+	localConfig := filepath.Join(path, "config.cfg")
+
+	values := make(map[string]string)
+	for key, value := range global.Values {
+		values[key] = value
+	}
+	for _, key := range []string{"SAVE_DIR", "MERGE_DIR", "DEFAULT_JOURNAL", "GIT_AUTOCOMMIT"} {
+		delete(values, key)
+	}
+	values["SAVE_DIR"] = path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		localConfig = path
+		values["SAVE_DIR"] = filepath.Join(project, "journal")
+	}
+
+	if fileExists(localConfig) {
+		local, err := readConfigFile(localConfig)
+		if err != nil {
+			return Config{}, err
+		}
+		if len(local.Names) > 0 {
+			return Config{}, fmt.Errorf("%s: [journal] sections only go in %s", localConfig, configPath)
+		}
+		for key, value := range local.Values {
+			if key == "SAVE_DIR" || key == "TEMPLATE_DIR" {
+				value = expandPath(value, project)
+			}
+			values[key] = value
+		}
+		delete(values, "DEFAULT_JOURNAL")
+	}
+
+	c, err := buildConfig(configFile{Values: values}, "default")
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", localConfig, err)
+	}
+	c.Journal = "local"
+	return c, nil
+}
+
+const localConfigTemplate = `#Project journal for this directory and everything below it.
+#Entries are kept next to this file. Any key from the main config can be
+#overridden here; relative SAVE_DIR and TEMPLATE_DIR are taken from the project
+#TEMPLATE=entry_template
+#GIT_AUTOCOMMIT=false
+`
+
+// init -local: a .journalz journal in the working directory
+func initLocalJournal(force bool) {
+	path := ".journalz"
+	if fileExists(path) && !force {
+		fmt.Println("Exists, skipping (use -force to rewrite its config.cfg):", path)
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(path, ".merges"), 0755); err != nil {
+		fmt.Println("Error creating local journal:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(path, "config.cfg"), []byte(localConfigTemplate), 0644); err != nil {
+		fmt.Println("Error writing local config:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Created local journal", path)
+}
+
+// Lists every journal with where it lives and how many entries it holds
+func listJournals() {
+	if err := ensureConfigFile(); err != nil {
+		fmt.Println("Error loading config file:", err)
+		os.Exit(1)
+	}
+	file, err := readConfigFile(configPath)
+	if err != nil {
+		fmt.Println("Error loading config file:", err)
+		os.Exit(1)
+	}
+
+	local, inLocal := findLocalJournal()
+	current := journalName
+	if current == "" && inLocal {
+		current = "local"
+	}
+
+	names := file.journalNames()
+	if inLocal {
+		names = append(names, "local")
+	}
+	for _, name := range names {
+		var c Config
+		if name == "local" && inLocal {
+			c, err = buildLocalConfig(file, local)
+		} else {
+			c, err = buildConfig(file, name)
+		}
+		if err != nil {
+			fmt.Println(theme.Error + "  " + name + ": " + err.Error() + theme.Reset)
+			continue
+		}
+
+		marker := "  "
+		if name == current || (current == "" && name == c.DefaultJournal) {
+			marker = "* "
+		}
+		line := marker + theme.Emphasis + name + theme.Reset
+		if name == c.DefaultJournal {
+			line += " (default)"
+		}
+
+		// Just count files, reading them would ask for the passphrase
+		entries := len(markdownNames(c.SaveDir))
+		merges := len(markdownNames(c.MergeDir))
+		if filepath.Clean(c.MergeDir) == filepath.Clean(c.SaveDir) {
+			merges = 0
+		}
+		fmt.Printf("%s  %d entries, %d merges  %s\n", line, entries, merges, theme.Faint+c.SaveDir+theme.Reset)
+	}
+}
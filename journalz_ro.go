@@ -1,10 +1,9 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,6 +12,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/term"
 )
 
 // Reset
@@ -71,17 +72,16 @@ const (
 )
 
 var scriptDir string = "/usr/local/bin/jz_ro-build/"
-var configPath string = os.Getenv("HOME") + "/.config/journal_zro/config.cfg"
-var subcommands = []string{"'new'", "'find'", "'merge'"}
-var config map[string]string = make(map[string]string)
+var configPath string = defaultConfigPath()
+var subcommands = []string{"'new'", "'find'", "'tags'", "'random'", "'onthisday'", "'review'", "'log'", "'export'", "'import'", "'serve'", "'init'", "'config'", "'journals'", "'stats'", "'calendar'", "'links'", "'graph'", "'related'", "'suggest-tags'", "'saved'", "'tag'"}
+var cfg Config
 var resultsList []Entry
-var ignoreList []string
 var mergeList []Entry
 
 // Defaults
 var SAVEDIR string = os.Getenv("HOME") + "/Documents/Journal_Zro/"
 var MERGE_DIR string = SAVEDIR + ".merges/"
-var TEMPLATE string = scriptDir + "/entry_template.md"
+var TEMPLATE string = scriptDir + "/" + defaultTemplate
 
 type Entry struct {
 	Path           string
@@ -98,109 +98,121 @@ func fileExists(filename string) bool {
 	}
 	return err == nil
 }
-func loadConfig(scriptPath string) (map[string]string, error) {
-	configDir := os.Getenv("HOME") + "/.config/journal_zro/"
-
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
+func countEntries() (int, error) {
+	files, err := os.ReadDir(SAVEDIR)
+	if err != nil {
+		return 0, err
+	}
 
-		err := os.MkdirAll(configDir, 0755)
-		if err != nil {
-			fmt.Println("Error creating config directory", err)
-			return nil, err
+	mdRegex := regexp.MustCompile(`\.md$`)
+	mdCount := 0
+	for _, file := range files {
+		if !file.IsDir() && mdRegex.MatchString(file.Name()) {
+			mdCount++
 		}
 	}
+	return mdCount, nil
+}
 
-	if !fileExists(configPath) {
-		newConf, err := os.OpenFile(configPath, os.O_RDWR|os.O_CREATE, 0644)
+// Entry<count>.md, moving on to the next number if that name is already taken
+func nextEntryName() (string, error) {
+	entryCount, err := countEntries()
+	if err != nil {
+		return "", err
+	}
+	for fileExists(filepath.Join(SAVEDIR, "Entry"+strconv.Itoa(entryCount)+".md")) {
+		entryCount++
+	}
+	return "Entry" + strconv.Itoa(entryCount) + ".md", nil
+}
+func openNvim(filePath string, insertMode bool) {
+	// Encrypted entries are only ever edited through a private decrypted copy
+	editPath := filePath
+	wasEncrypted := isEncryptedFile(filePath)
+	var original []byte
+	if wasEncrypted || encryptionMode() != "off" {
+		tempPath, cleanup, err := decryptToTemp(filePath)
 		if err != nil {
-			return nil, err
+			fmt.Println("Error decrypting entry", err)
+			return
 		}
-		defer newConf.Close()
+		defer cleanup()
+		editPath = tempPath
+		original, _ = os.ReadFile(tempPath)
+	}
 
-		defaultConfig, err := os.OpenFile(scriptPath+"/default.cfg", os.O_RDWR, 0755)
-		if err != nil {
-			return nil, err
-		}
-		defer defaultConfig.Close()
+	cmdArgs := []string{"-e", cfg.Editor, "+" + strconv.Itoa(cfg.StartPos), editPath}
 
-		_, errCopy := io.Copy(newConf, defaultConfig)
-		if errCopy != nil {
-			fmt.Println("Error copying default.cfg to config file. ", err)
-			return nil, err
-		}
+	if insertMode && strings.HasSuffix(cfg.Editor, "vim") {
+		cmdArgs = append(cmdArgs, "-c", "startinsert")
+	}
+	// A decrypted copy has to last until the editor itself exits, not just
+	// the terminal
+	var err error
+	if editPath == filePath {
+		err = exec.Command(cfg.TerminalApp, cmdArgs...).Run()
+	} else {
+		err = runEditorAndWait(cfg.TerminalApp, cmdArgs[1:])
 	}
-
-	file, err := os.OpenFile(configPath, os.O_RDWR, 0644)
 	if err != nil {
-		return nil, err
+		fmt.Println("Error opening terminal or editor", err)
+		return
 	}
-	defer file.Close()
-
-	// Read the file line by line
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
 
-		// Ignore empty lines and comments
-		if len(line) == 0 || strings.HasPrefix(line, "#") {
-			continue
+	if editPath != filePath {
+		edited, err := os.ReadFile(editPath)
+		if err != nil {
+			fmt.Println("Error reading edited entry", err)
+			return
 		}
-
-		// Split the line into key=value
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid config line: %s", line)
+		// Stay encrypted if encryption was switched off after the fact
+		encrypt := shouldEncrypt(sectionLines(edited, "Tags_", "_Tags")) || (wasEncrypted && encryptionMode() == "off")
+		if !bytes.Equal(edited, original) || encrypt != wasEncrypted {
+			if err := writeEntryFile(filePath, edited, encrypt); err != nil {
+				fmt.Println("Error saving entry", err)
+				return
+			}
 		}
-
-		// Trim spaces and store key-value pair
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		config[key] = value
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if err := recordView(filePath); err != nil {
+		fmt.Println("Error recording view", err)
 	}
-
-	return config, nil
 }
-func countEntries() (int, error) {
-	files, err := os.ReadDir(SAVEDIR)
+
+// Runs the editor through the terminal app and returns once the editor has
+// exited. Some terminals hand the window to a server and return straight
+// away, so the editor runs under a shell that leaves a file behind when it
+// is done, however it ends
+func runEditorAndWait(terminalApp string, editorArgs []string) error {
+	dir, err := os.MkdirTemp(os.Getenv("XDG_RUNTIME_DIR"), "journalz_ro-")
 	if err != nil {
-		return 0, err
+		return err
 	}
+	defer os.RemoveAll(dir)
+	done := filepath.Join(dir, "done")
 
-	mdRegex := regexp.MustCompile(`\.md$`)
-	mdCount := 0
-	for _, file := range files {
-		if !file.IsDir() && mdRegex.MatchString(file.Name()) {
-			mdCount++
-		}
+	script := `done=$1; shift; trap ': > "$done"' EXIT; trap 'exit 1' HUP INT TERM; "$@"`
+	args := append([]string{"-e", "sh", "-c", script, "sh", done}, editorArgs...)
+	if err := exec.Command(terminalApp, args...).Run(); err != nil {
+		return err
 	}
-	return mdCount, nil
-}
-func openNvim(filePath string, insertMode bool) {
-	cmdArgs := []string{"-e", "nvim", "+" + config["START_POS"], filePath}
-
-	if insertMode {
-		cmdArgs = append(cmdArgs, "-c", "startinsert")
+	if !fileExists(done) {
+		fmt.Println("Waiting for the editor to close...")
 	}
-	cmd := exec.Command(config["TERMINAL_APP"], cmdArgs...)
-	if err := cmd.Run(); err != nil {
-		fmt.Println("Error opening terminal or neovim", err)
-		return
+	for !fileExists(done) {
+		time.Sleep(200 * time.Millisecond)
 	}
+	return nil
 }
 func createEntry() {
 	currentDate := time.Now().Format("01/02/2006")
 
-	entryCount, err := countEntries()
+	title, err := nextEntryName()
 	if err != nil {
 		fmt.Println("Error counting entries")
 		return
 	}
 
-	title := "Entry" + strconv.Itoa(entryCount) + ".md"
 	filepath := SAVEDIR + "/" + title
 	file, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
@@ -209,7 +221,7 @@ func createEntry() {
 	}
 	defer file.Close()
 
-	newNote, err := os.ReadFile(TEMPLATE)
+	newNote, err := readTemplate(TEMPLATE)
 	if err != nil {
 		fmt.Println("Error reading template file:", err)
 		return
@@ -218,7 +230,9 @@ func createEntry() {
 	re := regexp.MustCompile(`MM/DD/YYYY`)
 	newNote = re.ReplaceAll(newNote, []byte(currentDate))
 
-	err = os.WriteFile(filepath, newNote, 0644)
+	// With encryption on the new entry is sealed from the start, it is only
+	// ever plaintext in the editor's private copy
+	err = writeEntryFile(filepath, newNote, encryptionMode() != "off")
 	if err != nil {
 		fmt.Println("Error copying default.cfg to config file. ", err)
 		return
@@ -226,114 +240,243 @@ func createEntry() {
 
 	openNvim(filepath, true)
 
-}
-func findEntries(args []string, entries []Entry) {
-	findCmd := flag.NewFlagSet("find", flag.ExitOnError)
-
-	// Flags
-	inclusive := findCmd.Bool("i", false, "Inclusive search: show entries which include ANY of the provided tags (default: all tags must match)")
-	first := findCmd.Bool("f", false, "Return only the first file to match the provided tags")
-	ascending := findCmd.Bool("a", false, "Sort by date/time in ascending order")
-	descending := findCmd.Bool("d", false, "Sort by date/time in descending order")
-	originalsOnly := findCmd.Bool("o", false, "Originals only, do not include merged entries in the results (default: prioritize merge entries and ignore originals if they're contained in a merge)")
-
-	findCmd.Parse(args)
-
-	searchTags := findCmd.Args()
-	if len(searchTags) == 0 {
-		fmt.Println("Error: You must provide at least one tag to find.")
-		os.Exit(1)
+	// Only when someone is there to answer, `new` is often run from a hotkey
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		if _, _, err := offerTagSuggestions(filepath); err != nil {
+			fmt.Println("Error suggesting tags:", err)
+		}
 	}
 
-	//Make map for comparison later
-	searchTagSet := make(map[string]bool)
-	for i := range searchTags {
-		searchTags[i] = strings.ToLower(strings.Trim(searchTags[i], " "))
-		searchTagSet[searchTags[i]] = true
+	tags, err := getLines(filepath, "Tags_", "_Tags")
+	if err != nil {
+		fmt.Println("Error fetching tags from file:", err)
 	}
+	gitCommit(describeEntry("Add", title, tags))
+}
 
-	// Walk the directory or search previous results
-	if entries != nil {
-		for _, entry := range entries {
-			if matchesTags(entry.Tags, searchTagSet, *inclusive) {
-				resultsList = append(resultsList, entry)
-			}
-		}
+type searchOptions struct {
+	Inclusive     bool
+	OriginalsOnly bool
+	Ascending     bool
+	Descending    bool
+	// Case-insensitive text the body has to contain, if set
+	Text string
+	// Header dates the entry has to fall between (inclusive), if set
+	From time.Time
+	To   time.Time
+	// Merges are listed whatever their tags
+	AllMerges bool
+}
 
-	} else {
+// Options for a search of the whole journal the way find runs one, where
+// merges are listed whatever their tags. The API builds its searches here
+// too, so both list the same entries
+func journalSearchOptions(inclusive bool, originalsOnly bool, ascending bool, descending bool) searchOptions {
+	return searchOptions{
+		Inclusive:     inclusive,
+		OriginalsOnly: originalsOnly,
+		Ascending:     ascending,
+		Descending:    descending,
+		AllMerges:     true,
+	}
+}
 
-		err := filepath.Walk(SAVEDIR, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
+// Filters entries the way find does: by tags, then either hiding originals
+// already contained in a merge in the results or (OriginalsOnly) hiding merges
+func searchEntries(entries []Entry, searchTagSet map[string]bool, opts searchOptions) []Entry {
+	var results []Entry
+	for _, entry := range entries {
+		if opts.OriginalsOnly && entry.MergeOriginals != nil {
+			continue
+		}
+		if len(searchTagSet) > 0 && !(opts.AllMerges && entry.MergeOriginals != nil) && !matchesTags(entry.Tags, searchTagSet, opts.Inclusive) {
+			continue
+		}
+		if !opts.From.IsZero() || !opts.To.IsZero() {
+			date, err := entryDate(entry)
+			if err != nil || (!opts.From.IsZero() && date.Before(opts.From)) || (!opts.To.IsZero() && date.After(opts.To)) {
+				continue
 			}
-
-			if !info.IsDir() {
-				tags, err := getLines(path, "Tags_", "_Tags")
-				if err != nil {
-					fmt.Println("Error fetching tags from file:", err)
-					return err
-				}
-				if strings.Contains(path, MERGE_DIR) {
-					if !*originalsOnly {
-						originalEntries, err := getLines(path, "Originals_", "_Originals")
-						if err != nil {
-							fmt.Println("Error fetching Originals from file:", err)
-							return err
-						}
-						resultsList = append(resultsList, Entry{Path: path, Info: info, MergeOriginals: originalEntries, Tags: tags})
-					}
-				} else {
-					if matchesTags(tags, searchTagSet, *inclusive) {
-						resultsList = append(resultsList, Entry{Path: path, Info: info, MergeOriginals: nil, Tags: tags})
-					}
-				}
+		}
+		if opts.Text != "" {
+			body, err := getLines(entry.Path, "Entry_", "_Entry")
+			if err != nil || !strings.Contains(strings.ToLower(strings.Join(body, "\n")), strings.ToLower(opts.Text)) {
+				continue
 			}
-			return nil
-		})
-
-		if err != nil && err.Error() != "stop early" {
-			fmt.Println("Error walking file tree:", err)
-			os.Exit(1)
 		}
+		results = append(results, entry)
 	}
+
 	// Default
-	if !*originalsOnly {
-		var filtered []Entry
-		for _, res := range resultsList {
+	if !opts.OriginalsOnly {
+		var ignoreList []string
+		for _, res := range results {
 			if res.MergeOriginals != nil {
 				ignoreList = append(ignoreList, res.MergeOriginals...)
 			}
 		}
-		for _, res := range resultsList {
-			if !contains(ignoreList, res.Info.Name()) {
-				filtered = append(filtered, res)
-			}
-		}
-		resultsList = filtered
-	} else {
 		var filtered []Entry
-		for _, res := range resultsList {
-			if res.MergeOriginals == nil {
+		for _, res := range results {
+			if !contains(ignoreList, res.Info.Name()) {
 				filtered = append(filtered, res)
 			}
 		}
-		resultsList = filtered
+		results = filtered
 	}
 
+	// Sort results by date if necessary
+	if opts.Ascending {
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].Info.ModTime().Before(results[j].Info.ModTime())
+		})
+	} else if opts.Descending {
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].Info.ModTime().After(results[j].Info.ModTime())
+		})
+	}
+	return results
+}
+
+// A find command line, parsed
+type findQuery struct {
+	Tags    []string
+	Options searchOptions
+	First   bool
+	// Name to save the search under, with the flags to save
+	Save  string
+	Flags []string
+	// Tags and date range the way the results heading shows them
+	Labels []string
+}
+
+// Parses find's flags and tags, @name saved searches included. Unknown
+// flags exit like any flag set does, anything else is returned
+func parseFindArgs(args []string) (findQuery, error) {
+	var query findQuery
+	findCmd := flag.NewFlagSet("find", flag.ExitOnError)
+
+	// Flags
+	inclusive := findCmd.Bool("i", false, "Inclusive search: show entries which include ANY of the provided tags (default: all tags must match)")
+	first := findCmd.Bool("f", false, "Return only the first file to match the provided tags")
+	ascending := findCmd.Bool("a", false, "Sort by date/time in ascending order")
+	descending := findCmd.Bool("d", false, "Sort by date/time in descending order")
+	originalsOnly := findCmd.Bool("o", false, "Originals only, do not include merged entries in the results (default: prioritize merge entries and ignore originals if they're contained in a merge)")
+	from := findCmd.String("from", "", "Only entries dated on or after this day: MM/DD/YYYY, YYYY-MM-DD or days ago like 30d")
+	to := findCmd.String("to", "", "Only entries dated on or before this day: MM/DD/YYYY, YYYY-MM-DD or days ago like 7d")
+	save := findCmd.String("save", "", "Save this search under a name, to run again with find @name")
+
+	// @name runs a saved search
+	args, err := expandSavedSearches(args)
+	if err != nil {
+		return query, err
+	}
+	// Flags may also follow the tags, e.g. find finance tax -save taxes. A
+	// lone - is a tag, flag would stop at it without taking it
+	for findCmd.Parse(args); findCmd.NArg() > 0; findCmd.Parse(args) {
+		rest := findCmd.Args()
+		i := 0
+		for i < len(rest) && (!strings.HasPrefix(rest[i], "-") || rest[i] == "-") {
+			i++
+		}
+		query.Tags = append(query.Tags, rest[:i]...)
+		if i == len(rest) {
+			break
+		}
+		args = rest[i:]
+	}
+	if len(query.Tags) == 0 && *from == "" && *to == "" {
+		return query, fmt.Errorf("you must provide at least one tag (or a date range) to find")
+	}
 	if *ascending && *descending {
-		fmt.Println("Error: cannot sort by both asc and desc")
+		return query, fmt.Errorf("cannot sort by both asc and desc")
+	}
+
+	for i := range query.Tags {
+		query.Tags[i] = normalizeTag(query.Tags[i])
+	}
+	query.Labels = append([]string{}, query.Tags...)
+	query.Options = journalSearchOptions(*inclusive, *originalsOnly, *ascending, *descending)
+	today := time.Now()
+	if *from != "" {
+		query.Options.From, err = parseDateFlag(*from, today)
+		if err != nil {
+			return query, fmt.Errorf("-from %w", err)
+		}
+		query.Labels = append(query.Labels, "from "+query.Options.From.Format("01/02/2006"))
+	}
+	if *to != "" {
+		query.Options.To, err = parseDateFlag(*to, today)
+		if err != nil {
+			return query, fmt.Errorf("-to %w", err)
+		}
+		query.Labels = append(query.Labels, "to "+query.Options.To.Format("01/02/2006"))
+	}
+	query.First = *first
+	query.Save = *save
+	query.Flags = setFlags(findCmd)
+	return query, nil
+}
+
+// Entries matching the query, out of entries or the whole journal when nil
+func (query findQuery) search(entries []Entry) ([]Entry, error) {
+	if entries == nil {
+		var err error
+		entries, err = loadEntries()
+		if err != nil {
+			return nil, err
+		}
+	}
+	//Make map for comparison later
+	searchTagSet := make(map[string]bool)
+	for _, tag := range query.Tags {
+		searchTagSet[tag] = true
+	}
+	return searchEntries(entries, searchTagSet, query.Options), nil
+}
+
+func findEntries(args []string, entries []Entry) {
+	query, err := parseFindArgs(args)
+	if err != nil {
+		fmt.Println("Error:", err)
 		os.Exit(1)
 	}
 
-	// Sort results by date if necessary
-	if *ascending {
-		sort.Slice(resultsList, func(i, j int) bool {
-			return resultsList[i].Info.ModTime().Before(resultsList[j].Info.ModTime())
-		})
-	} else if *descending {
-		sort.Slice(resultsList, func(i, j int) bool {
-			return resultsList[i].Info.ModTime().After(resultsList[j].Info.ModTime())
+	message := ""
+	if query.Save != "" {
+		err := saveSearch(savedSearch{Name: query.Save, Flags: query.Flags, Tags: query.Tags})
+		if err != nil {
+			fmt.Println("Error saving search:", err)
+			os.Exit(1)
+		}
+		message = "Saved as @" + query.Save
+		fmt.Println(message)
+	}
+
+	// Walk the directory or search previous results. A new search adds to
+	// the results already listed, refining narrows them down
+	if entries == nil {
+		found, err := query.search(nil)
+		if err != nil {
+			fmt.Println("Error walking file tree:", err)
+			os.Exit(1)
+		}
+		listed := make(map[string]bool)
+		for _, entry := range resultsList {
+			listed[entry.Path] = true
+		}
+		for _, entry := range found {
+			if !listed[entry.Path] {
+				resultsList = append(resultsList, entry)
+			}
+		}
+		resultsList = searchEntries(resultsList, nil, searchOptions{
+			OriginalsOnly: query.Options.OriginalsOnly,
+			Ascending:     query.Options.Ascending,
+			Descending:    query.Options.Descending,
 		})
+	} else {
+		query.Options.AllMerges = false
+		resultsList, _ = query.search(entries)
 	}
 
 	//Display Results
@@ -341,10 +484,10 @@ func findEntries(args []string, entries []Entry) {
 		fmt.Println("No entries found with these parameters")
 		os.Exit(0)
 	} else {
-		if *first {
+		if query.First {
 			openNvim(resultsList[0].Path, false)
 		} else {
-			optionsPrompt("RESULTS", resultsList, searchTags, "")
+			optionsPrompt("RESULTS", resultsList, query.Labels, message)
 			return
 		}
 	}
@@ -352,59 +495,69 @@ func findEntries(args []string, entries []Entry) {
 }
 func optionsPrompt(title string, entriesList []Entry, searchTags []string, message string) {
 	clearTerminal()
-	fmt.Println(Green, "SEARCH TAGS = ", Reset, strings.Join(searchTags, ","))
+	fmt.Println(theme.Heading, "SEARCH TAGS = ", theme.Reset, strings.Join(searchTags, ","))
 	fmt.Println("")
 	switch title {
 	case "MERGE LIST":
-		fmt.Println(Blue, "=======MERGE LIST===============================================================", Reset)
+		fmt.Println(theme.Title, "=======MERGE LIST===============================================================", theme.Reset)
 	case "RESULTS":
-		fmt.Println(Blue, "=========RESULTS================================================================", Reset)
+		fmt.Println(theme.Title, "=========RESULTS================================================================", theme.Reset)
 	default:
-		fmt.Println(BgBrightGreen, BrightCyan, "=========+++++++================================================================", Reset)
+		fmt.Println(theme.Banner, "=========+++++++================================================================", theme.Reset)
 	}
 	fmt.Println("")
 	displayEntries(entriesList)
-	fmt.Println(BrightMagenta, "=========OPTIONS================================================================", Reset)
+	fmt.Println(theme.Options, "=========OPTIONS================================================================", theme.Reset)
 	//Prompt User
 	if title == "RESULTS" {
 		// E.g. r -i finance
-		fmt.Print(Magenta, "[R]efine current search: ", Reset, "r -[opts] [tag]...\n")
+		fmt.Print(theme.Option, "[R]efine current search: ", theme.Reset, "r -[opts] [tag]...\n")
 		// E.g. n -a health
-		fmt.Print(Magenta, "[N]ew search: ", Reset, "n -[opts] [tag]...\n")
-		// E.g. a 1 4 12
-		fmt.Print(Magenta, "[A]dd entry to merge list: ", Reset, "a [number]...\n")
+		fmt.Print(theme.Option, "[N]ew search: ", theme.Reset, "n -[opts] [tag]...\n")
+		// E.g. a 1-5,8 ^3
+		fmt.Print(theme.Option, "[A]dd entries to merge list: ", theme.Reset, "a [selection]\n")
 		// E.g. w
-		fmt.Print(Magenta, "[W]hole list to merge list: ", Reset, "w\n")
-		// E.g. d 1 4 12
-		fmt.Print(Magenta, "[D]elete entry permanently: ", Reset, "d [number]...\n")
+		fmt.Print(theme.Option, "[W]hole list to merge list: ", theme.Reset, "w\n")
+		// E.g. d 10-
+		fmt.Print(theme.Option, "[D]elete entries permanently: ", theme.Reset, "d [selection]\n")
+		// E.g. s 3
+		fmt.Print(theme.Option, "[S]imilar entries to one result: ", theme.Reset, "s [number]\n")
+		// E.g. @taxes
+		if names := savedSearchNames(); len(names) > 0 {
+			fmt.Print(theme.Option, "[@]Run a saved search: ", theme.Reset, "@[name] ("+strings.Join(names, ", ")+")\n")
+		}
 	}
 	if title == "MERGE LIST" {
 		// E.g. m 2024
-		fmt.Print(Magenta, "[M]erge entries from merge list to single entry: ", Reset, "m [name]...\n")
-		// E.g. d 2 12 6
-		fmt.Print(Magenta, "[D]elete entries from merge list: ", Reset, "d [number]...\n")
-		fmt.Print(Magenta, "[B]ack to results: ", Reset, "b\n")
+		fmt.Print(theme.Option, "[M]erge entries from merge list to single entry: ", theme.Reset, "m [name]...\n")
+		// E.g. d 2,6 12
+		fmt.Print(theme.Option, "[D]elete entries from merge list: ", theme.Reset, "d [selection]\n")
+		fmt.Print(theme.Option, "[B]ack to results: ", theme.Reset, "b\n")
 	} else {
 		// E.g. v
-		fmt.Print(Magenta, "[V]iew current merge list: ", Reset, "v\n")
-		// E.g. q
-		fmt.Print(Magenta, "[Q]uit: ", Reset, "q\n")
-		// E.g. 31
-		fmt.Print(Magenta, "[#] Number of the file to open: ", Reset, "[number]\n")
+		fmt.Print(theme.Option, "[V]iew current merge list: ", theme.Reset, "v\n")
 	}
+	// E.g. t+ work/projectx 1-3 or t- draft *
+	fmt.Print(theme.Option, "[T]ag or untag entries: ", theme.Reset, "t+ [tag] [selection], t- [tag] [selection] (* for all listed)\n")
+	// E.g. q
+	fmt.Print(theme.Option, "[Q]uit: ", theme.Reset, "q\n")
+	// E.g. 31 or 2-4
+	fmt.Print(theme.Option, "[#] Numbers of the files to open: ", theme.Reset, "[selection], e.g. 1-5,8 ^3 or *\n")
 	if message != "" {
-		fmt.Println(Red, "==========INFO==================================================================", Reset)
-		fmt.Println(Red, message, Reset)
+		fmt.Println(theme.Info, "==========INFO==================================================================", theme.Reset)
+		fmt.Println(theme.Info, message, theme.Reset)
 	}
-	fmt.Print("Your decision: ")
-
-	var input string
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() {
-		input = scanner.Text()
+	input, err := promptLine("Your decision: ", promptCompletions(title))
+	if err != nil {
+		// Out of input (or Ctrl-C), same as quitting
+		os.Exit(0)
 	}
 
 	inputArr := strings.Split(strings.Trim(input, " "), " ")
+	if strings.TrimSpace(input) == "" {
+		optionsPrompt(title, entriesList, searchTags, "")
+		return
+	}
 	newCmd := inputArr[0]
 	newArgs := inputArr[1:]
 
@@ -420,31 +573,80 @@ func optionsPrompt(title string, entriesList []Entry, searchTags []string, messa
 		case "n":
 			findEntries(newArgs, nil)
 		case "a":
-			var tempList []string
-			for _, arg := range newArgs {
-				selectedNumber, err := strconv.Atoi(arg)
-				if err != nil || selectedNumber < 1 || selectedNumber > len(entriesList) {
-					fmt.Println("Invalid selection:"+arg, err)
+			selection, err := parseSelection(newArgs, len(entriesList))
+			if err != nil {
+				optionsPrompt("RESULTS", resultsList, searchTags, "Invalid selection: "+err.Error())
+				return
+			}
+			var added []string
+			for _, entry := range selectEntries(entriesList, selection) {
+				if !contains(fileNames(mergeList), entry.Info.Name()) {
+					mergeList = append(mergeList, entry)
+					added = append(added, entry.Info.Name())
 				}
-				tempList = append(tempList, arg)
-				mergeList = append(mergeList, entriesList[selectedNumber-1])
 			}
-			msg := strings.Join(tempList, ", ") + " added to merge list"
-			optionsPrompt("RESULTS", resultsList, searchTags, msg)
+			if len(added) == 0 {
+				optionsPrompt("RESULTS", resultsList, searchTags, "Already in the merge list")
+				return
+			}
+			optionsPrompt("RESULTS", resultsList, searchTags, strings.Join(added, ", ")+" added to merge list")
 			return
 		case "w":
 			mergeList = append(mergeList, resultsList...)
 			optionsPrompt("RESULTS", resultsList, searchTags, "Whole of results added to merge list")
 			return
 		case "d":
-			// TODO add confirmation
-			for _, arg := range newArgs {
-				selectedNumber, err := strconv.Atoi(arg)
-				if err != nil || selectedNumber < 1 || selectedNumber > len(entriesList) {
-					fmt.Println("Invalid selection:"+arg, err)
+			selection, err := parseSelection(newArgs, len(entriesList))
+			if err != nil {
+				optionsPrompt("RESULTS", resultsList, searchTags, "Invalid selection: "+err.Error())
+				return
+			}
+			selected := selectEntries(entriesList, selection)
+			answer, err := readAnswer(theme.Warning + "Delete " + strings.Join(fileNames(selected), ", ") + " permanently?" + theme.Reset + " [y/N] ")
+			if err != nil || strings.ToLower(strings.TrimSpace(answer)) != "y" {
+				optionsPrompt("RESULTS", resultsList, searchTags, "Nothing deleted")
+				return
+			}
+			var deleted []string
+			for _, entry := range selected {
+				if err := os.Remove(entry.Path); err != nil {
+					fmt.Println("Error deleting "+entry.Info.Name(), err)
+					continue
 				}
-				os.Remove(entriesList[selectedNumber-1].Path)
+				deleted = append(deleted, entry.Info.Name())
+			}
+			gitCommit("Delete " + strings.Join(deleted, ", "))
+
+			// Deleted entries can't stay listed or be merged
+			var kept []Entry
+			for _, entry := range mergeList {
+				if !contains(deleted, entry.Info.Name()) {
+					kept = append(kept, entry)
+				}
+			}
+			mergeList = kept
+			resultsList = withoutSelected(entriesList, selection)
+			optionsPrompt("RESULTS", resultsList, searchTags, "Deleted "+strings.Join(deleted, ", "))
+			return
+		case "s":
+			selection, err := parseSelection(newArgs, len(entriesList))
+			if err != nil || len(selection) != 1 {
+				optionsPrompt("RESULTS", resultsList, searchTags, "Pick one result to find entries similar to, e.g. s 3")
+				return
+			}
+			target := entriesList[selection[0]]
+			related, err := relatedResults(target, 20)
+			if err != nil || len(related) == 0 {
+				optionsPrompt("RESULTS", resultsList, searchTags, "No entries similar to "+target.Info.Name())
+				return
 			}
+			resultsList = related
+			optionsPrompt("RESULTS", resultsList, []string{"similar to " + target.Info.Name()}, "")
+			return
+		case "t+", "t-":
+			message := promptRetag(strings.ToLower(newCmd), newArgs, entriesList)
+			optionsPrompt("RESULTS", resultsList, searchTags, message)
+			return
 		case "v":
 			if len(mergeList) > 0 {
 				optionsPrompt("MERGE LIST", mergeList, searchTags, "")
@@ -457,18 +659,27 @@ func optionsPrompt(title string, entriesList []Entry, searchTags []string, messa
 		case "q":
 			os.Exit(0)
 		default:
-			selectedNumber, err := strconv.Atoi(input)
-			if err != nil || selectedNumber < 1 || selectedNumber > len(entriesList) {
-				fmt.Println("Invalid selection. Please enter a valid option.")
-				optionsPrompt("RESULTS", resultsList, searchTags, "")
+			if strings.HasPrefix(newCmd, "@") {
+				findEntries(inputArr, nil)
+				return
+			}
+			selection, err := parseSelection(inputArr, len(entriesList))
+			if err != nil {
+				optionsPrompt("RESULTS", resultsList, searchTags, "Invalid selection: "+err.Error())
 				return
 			}
-			openNvim(entriesList[selectedNumber-1].Path, false)
+			for _, entry := range selectEntries(entriesList, selection) {
+				openNvim(entry.Path, false)
+			}
 		}
 	} else if title == "MERGE LIST" {
 		switch strings.ToLower(newCmd) {
 		case "m":
-			if len(mergeList) > 1 && newArgs[0] != "" {
+			if strings.TrimSpace(strings.Join(newArgs, "")) == "" {
+				optionsPrompt("MERGE LIST", mergeList, searchTags, "Name the merge, e.g. m 2024 taxes")
+				return
+			}
+			if len(mergeList) > 1 {
 				newMerge, err := makeMergeEntry(strings.Join(newArgs, " "))
 				if err != nil {
 					fmt.Println("Error merging entries", err)
@@ -486,40 +697,40 @@ func optionsPrompt(title string, entriesList []Entry, searchTags []string, messa
 		case "b":
 			optionsPrompt("RESULTS", resultsList, searchTags, "")
 			return
+		case "t+", "t-":
+			message := promptRetag(strings.ToLower(newCmd), newArgs, entriesList)
+			optionsPrompt("MERGE LIST", mergeList, searchTags, message)
+			return
 		case "d":
-			if len(newArgs) > 0 {
-				for _, arg := range newArgs {
-					selectedNumber, err := strconv.Atoi(arg)
-					if err != nil || selectedNumber < 1 || selectedNumber > len(mergeList) {
-						optionsPrompt("MERGE LIST", mergeList, searchTags, "Invalid selection. Please enter a valid option.")
-						return
-					}
-
-					mergeList = append(
-						mergeList[:selectedNumber-1],
-						mergeList[selectedNumber:]...)
-				}
-				optionsPrompt("MERGE LIST", mergeList, searchTags, "Merge list updated")
+			selection, err := parseSelection(newArgs, len(mergeList))
+			if err != nil {
+				optionsPrompt("MERGE LIST", mergeList, searchTags, "Invalid selection: "+err.Error())
+				return
+			}
+			mergeList = withoutSelected(mergeList, selection)
+			if len(mergeList) == 0 {
+				optionsPrompt("RESULTS", resultsList, searchTags, "Merge list emptied")
 				return
 			}
+			optionsPrompt("MERGE LIST", mergeList, searchTags, "Merge list updated")
+			return
 		case "q":
 			os.Exit(0)
 		default:
-			selectedNumber, err := strconv.Atoi(input)
-			if err != nil || selectedNumber < 1 || selectedNumber > len(entriesList) {
-				fmt.Println("Invalid selection. Please enter a valid option.")
-				optionsPrompt("RESULTS", resultsList, searchTags, "")
+			selection, err := parseSelection(inputArr, len(entriesList))
+			if err != nil {
+				optionsPrompt("MERGE LIST", mergeList, searchTags, "Invalid selection: "+err.Error())
 				return
 			}
 			// TODO make new window optional
-			openNvim(entriesList[selectedNumber-1].Path, false)
+			for _, entry := range selectEntries(entriesList, selection) {
+				openNvim(entry.Path, false)
+			}
 		}
 	}
 
 }
 
-// TODO Random reminder function to show a random entry to remind you of it
-
 // TODO Add all feature to add all results to mergeList
 func makeMergeEntry(name string) (Entry, error) {
 	var newMerge Entry
@@ -556,33 +767,22 @@ func makeMergeEntry(name string) (Entry, error) {
 	allLines = append(allLines, newMerge.MergeOriginals...)
 	allLines = append(allLines, "## _Originals")
 
-	err := writeLines(newMerge.Path, allLines)
-	if err != nil {
-		fmt.Println("Error writing merge file", err)
-		os.Exit(1)
+	encrypt := shouldEncrypt(newMerge.Tags)
+	for _, entry := range mergeList {
+		encrypt = encrypt || isEncryptedFile(entry.Path)
 	}
-	return newMerge, nil
-}
-func writeLines(filePath string, lines []string) error {
-	file, err := os.Create(filePath)
+	err := writeEntryFile(newMerge.Path, []byte(strings.Join(allLines, "\n")+"\n"), encrypt)
 	if err != nil {
-		return fmt.Errorf("could not create file: %v", err)
-	}
-	defer file.Close()
-	writer := bufio.NewWriter(file)
-	for _, line := range lines {
-		_, err := writer.WriteString(line + "\n")
-		if err != nil {
-			return fmt.Errorf("could not write line: %v", err)
-		}
-	}
-	if err := writer.Flush(); err != nil {
-		return fmt.Errorf("could not flush to file: %v", err)
+		return newMerge, fmt.Errorf("error writing merge file: %w", err)
 	}
-
-	return nil
+	gitCommit(describeEntry("Merge", name+".md", newMerge.Tags) + "\n\nOriginals: " + strings.Join(newMerge.MergeOriginals, ", "))
+	return newMerge, nil
 }
 func clearTerminal() {
+	// Escape codes only make sense on a terminal
+	if !stdoutIsTerminal() {
+		return
+	}
 	cmd := exec.Command("clear")
 	cmd.Stdout = os.Stdout
 	err := cmd.Run()
@@ -591,22 +791,28 @@ func clearTerminal() {
 	}
 }
 func getDate(file string) (string, error) {
-	cmd := exec.Command("head", "-n", "1", file)
-	output, err := cmd.Output()
+	data, err := readEntryFile(file)
 	if err != nil {
 		return "", err
 	}
-	date := strings.Trim(string(output), " ")
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	date := strings.Trim(firstLine, " ")
 	return date, nil
 }
 func displayEntries(entries []Entry) error {
 
+	// Backlinks can come from anywhere in the journal, not just these results
+	var links linkIndex
+	if all, err := loadUnlockedEntries(); err == nil {
+		links = buildLinkIndex(all)
+	}
+
 	for i, entry := range entries {
 		date, err := getDate(entry.Path)
 		if err != nil {
 			fmt.Println("Error getting date from entry", err)
 		}
-		fmt.Println(Bold, Blue, strconv.Itoa(i+1)+") ", Reset, entry.Info.Name(), " | Created: ", date)
+		fmt.Println(theme.Number, strconv.Itoa(i+1)+") ", theme.Reset, entry.Info.Name(), " | Created: ", date+backlinkSummary(links, entry))
 		preview, err := getLines(entry.Path, "Entry_", "_Entry")
 		if err != nil {
 			fmt.Println("Error reading body of entry at "+entry.Path, err)
@@ -617,14 +823,14 @@ func displayEntries(entries []Entry) error {
 		} else {
 			for i, pLine := range preview {
 				if i < 5 {
-					fmt.Println("\t", Green+pLine, Reset)
+					fmt.Println("\t", theme.Text+pLine, theme.Reset)
 				}
 			}
 		}
 		//Separator
 		if i < len(entries)-1 {
 
-			fmt.Println(Yellow, "================================================================================", Reset)
+			fmt.Println(theme.Separator, "================================================================================", theme.Reset)
 		}
 	}
 	return nil
@@ -638,11 +844,14 @@ func contains(slice []string, target string) bool {
 	return false
 }
 func matchesTags(tags []string, searchTagSet map[string]bool, inclusive bool) bool {
+	tags = normalizeTags(tags)
 	if inclusive {
 		// Inclusive search: check if any tag matches
-		for _, tag := range tags {
-			if searchTagSet[strings.ToLower(tag)] {
-				return true
+		for sTag := range searchTagSet {
+			for _, tag := range tags {
+				if tagMatches(tag, sTag) {
+					return true
+				}
 			}
 		}
 		return false
@@ -651,7 +860,7 @@ func matchesTags(tags []string, searchTagSet map[string]bool, inclusive bool) bo
 		for sTag := range searchTagSet {
 			found := false
 			for _, tag := range tags {
-				if sTag == strings.ToLower(tag) {
+				if tagMatches(tag, sTag) {
 					found = true
 					break
 				}
@@ -664,36 +873,108 @@ func matchesTags(tags []string, searchTagSet map[string]bool, inclusive bool) bo
 	}
 }
 func getLines(filePath string, startMark string, endMark string) ([]string, error) {
-	cmd := exec.Command("sed", "-n", fmt.Sprintf("/## %s/,/## %s/p", startMark, endMark), filePath)
-	output, err := cmd.Output()
+	data, err := readEntryFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("error executing sed command: %w", err)
-	}
-	lines := strings.Split(string(output), "\n")
-	if len(lines) > 2 {
-		lines = lines[1 : len(lines)-2]
+		return nil, fmt.Errorf("error reading entry: %w", err)
 	}
+	return sectionLines(data, startMark, endMark), nil
+}
 
-	for _, line := range lines {
-		line = strings.Trim(line, " ")
+// Lines between "## start" and "## end", like the Tags_/_Tags markers in entries
+func sectionLines(data []byte, startMark string, endMark string) []string {
+	var lines []string
+	inSection := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if !inSection {
+			inSection = strings.Contains(line, "## "+startMark)
+			continue
+		}
+		if strings.Contains(line, "## "+endMark) {
+			break
+		}
+		lines = append(lines, line)
 	}
+	return lines
+}
 
-	return lines, nil
+// Replaces the lines between "## start" and "## end", leaving every other
+// byte of the entry as it was. New lines get the \r\n endings of the marker
+// in files that use them
+func replaceSection(data []byte, startMark string, endMark string, lines []string) ([]byte, error) {
+	all := strings.Split(string(data), "\n")
+	start := -1
+	for i, line := range all {
+		if start < 0 && strings.Contains(line, "## "+startMark) {
+			start = i
+			continue
+		}
+		if start >= 0 && strings.Contains(line, "## "+endMark) {
+			var replaced []string
+			replaced = append(replaced, all[:start+1]...)
+			for _, line := range lines {
+				if strings.HasSuffix(all[start], "\r") && !strings.HasSuffix(line, "\r") {
+					line += "\r"
+				}
+				replaced = append(replaced, line)
+			}
+			replaced = append(replaced, all[i:]...)
+			return []byte(strings.Join(replaced, "\n")), nil
+		}
+	}
+	return nil, fmt.Errorf("no %s section found", startMark)
 }
 func mergeEntries(list []Entry) {
 }
 func main() {
 
+	// -j name picks the journal for everything after it. Completion sees it
+	// after __complete, so the candidates come from the right journal there too
+	os.Args = append(os.Args[:1], takeGlobalFlags(os.Args[1:])...)
+	if len(os.Args) > 2 && os.Args[1] == "__complete" {
+		if name, rest, ok := takeJournalFlag(os.Args[2:]); ok && len(rest) > 0 {
+			journalName = name
+			os.Args = append(os.Args[:2], rest...)
+		}
+	}
+	if err := setTheme("default"); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Println("Expected " + strings.Join(subcommands, ", ") + " subcommands.")
+		os.Exit(1)
+	}
+
+	// Work without a valid config, they're how you get or fix one
+	switch os.Args[1] {
+	case "init":
+		initConfig(os.Args[2:])
+		return
+	case "config":
+		configCommand(os.Args[2:])
+		return
+	case "journals":
+		listJournals()
+		return
+	}
+
 	// Set config if exists; else create it
-	config, err := loadConfig(scriptDir)
+	c, err := loadConfig()
 	if err != nil {
 		fmt.Println("Error loading config file: ", err)
-		return
+		os.Exit(1)
 	}
-
-	if config["SAVE_DIR"] != "" {
-		SAVEDIR = os.Getenv("HOME") + "/" + config["SAVE_DIR"]
+	applyConfig(c)
+	if err := setTheme(c.Theme); err != nil {
+		fmt.Println("Error loading theme: ", err)
+		os.Exit(1)
 	}
+	if err := loadTagVocabulary(tagsPath); err != nil {
+		fmt.Println("Error loading tag vocabulary: ", err)
+		os.Exit(1)
+	}
+
 	if _, err := os.Stat(SAVEDIR); os.IsNotExist(err) {
 		err := os.MkdirAll(SAVEDIR, 0755)
 		if err != nil {
@@ -708,15 +989,19 @@ func main() {
 			return
 		}
 	}
-	if len(os.Args) < 2 {
-		fmt.Println("Expected " + strings.Join(subcommands, ", ") + "subcommands.")
-		os.Exit(1)
-	}
 
 	switch os.Args[1] {
 	case "new":
 		newCmd := flag.NewFlagSet("new", flag.ExitOnError)
+		templateName := newCmd.String("t", "", "Name of the template to use, from the template directory (default: entry_template)")
 		newCmd.Parse(os.Args[2:])
+		if *templateName != "" {
+			TEMPLATE = filepath.Join(filepath.Dir(TEMPLATE), *templateName+".md")
+			if !templateExists(TEMPLATE) {
+				fmt.Println("Unknown template: " + *templateName + ". Available: " + strings.Join(templateNames(), ", "))
+				os.Exit(1)
+			}
+		}
 		createEntry()
 	case "find":
 		if len(os.Args) > 2 {
@@ -724,8 +1009,44 @@ func main() {
 		} else {
 			fmt.Println("Error: You must provide at least one argument")
 		}
+	case "tags":
+		listTags(os.Args[2:])
+	case "random":
+		randomEntry(os.Args[2:])
+	case "onthisday":
+		onThisDay()
+	case "review":
+		reviewEntries(os.Args[2:])
+	case "log":
+		showLog(os.Args[2:])
+	case "export":
+		exportJournal(os.Args[2:])
+	case "import":
+		importJournal(os.Args[2:])
+	case "serve":
+		serve(os.Args[2:])
+	case "completion":
+		printCompletionScript(os.Args[2:])
+	case "stats":
+		showStats(os.Args[2:])
+	case "calendar":
+		showCalendar(os.Args[2:])
+	case "links":
+		showLinks(os.Args[2:])
+	case "graph":
+		exportGraph(os.Args[2:])
+	case "related":
+		showRelated(os.Args[2:])
+	case "suggest-tags":
+		suggestTagsCommand(os.Args[2:])
+	case "saved":
+		listSavedSearches(os.Args[2:])
+	case "tag":
+		tagCommand(os.Args[2:])
+	case "__complete":
+		complete(os.Args[2:])
 	default:
-		fmt.Println("Unknown command. Use 'new', 'find', or 'merge'.")
+		fmt.Println("Unknown command. Use " + strings.Join(subcommands, ", ") + ".")
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReplaceSection(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		lines []string
+		want  string
+	}{
+		{
+			"replaces only the section",
+			"10/10/2025\n---\n## Entry_\nbody\n## _Entry\n## Tags_\nold\n## _Tags\n",
+			[]string{"work", "tax"},
+			"10/10/2025\n---\n## Entry_\nbody\n## _Entry\n## Tags_\nwork\ntax\n## _Tags\n",
+		},
+		{
+			"empty section",
+			"## Tags_\n## _Tags",
+			[]string{"work"},
+			"## Tags_\nwork\n## _Tags",
+		},
+		{
+			"clears the section",
+			"## Tags_\nwork\n\n## _Tags\ntrailing",
+			nil,
+			"## Tags_\n## _Tags\ntrailing",
+		},
+		{
+			"crlf",
+			"10/10/2025\r\n---\r\n## Tags_\r\nwork\r\n## _Tags\r\n",
+			[]string{"work\r", "tax"},
+			"10/10/2025\r\n---\r\n## Tags_\r\nwork\r\ntax\r\n## _Tags\r\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := replaceSection([]byte(test.data), "Tags_", "_Tags", test.lines)
+			if err != nil {
+				t.Fatalf("replaceSection error: %v", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("replaceSection = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := replaceSection([]byte("## Entry_\n## _Entry\n"), "Tags_", "_Tags", nil); err == nil {
+		t.Error("replaceSection without a Tags_ section = nil error, want one")
+	}
+}
+
+func TestParseFindArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		tags      []string
+		inclusive bool
+		ascending bool
+		save      string
+		flags     []string
+	}{
+		{"tags only", []string{"work", "tax"}, []string{"work", "tax"}, false, false, "", nil},
+		{"flags first", []string{"-i", "-a", "work", "tax"}, []string{"work", "tax"}, true, true, "", []string{"-a", "-i"}},
+		{"flags after tags", []string{"finance", "tax", "-save", "taxes"}, []string{"finance", "tax"}, false, false, "taxes", nil},
+		{"flags between tags", []string{"work", "-i", "tax", "-a", "home"}, []string{"work", "tax", "home"}, true, true, "", []string{"-a", "-i"}},
+		{"lone dash", []string{"work", "-"}, []string{"work", "-"}, false, false, "", nil},
+		{"lone dash then flag", []string{"-", "-i", "work"}, []string{"-", "work"}, true, false, "", []string{"-i"}},
+		{"double dash", []string{"-i", "--", "work"}, []string{"work"}, true, false, "", []string{"-i"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := parseFindArgs(test.args)
+			if err != nil {
+				t.Fatalf("parseFindArgs(%q) error: %v", test.args, err)
+			}
+			if !slices.Equal(query.Tags, test.tags) {
+				t.Errorf("tags = %q, want %q", query.Tags, test.tags)
+			}
+			if query.Options.Inclusive != test.inclusive || query.Options.Ascending != test.ascending {
+				t.Errorf("inclusive, ascending = %v, %v, want %v, %v", query.Options.Inclusive, query.Options.Ascending, test.inclusive, test.ascending)
+			}
+			if query.Save != test.save {
+				t.Errorf("save = %q, want %q", query.Save, test.save)
+			}
+			if !slices.Equal(query.Flags, test.flags) {
+				t.Errorf("flags = %q, want %q", query.Flags, test.flags)
+			}
+		})
+	}
+}
+
+func TestParseFindArgsErrors(t *testing.T) {
+	for _, args := range [][]string{{}, {"-i"}, {"-a", "-d", "work"}, {"-from", "yesterday", "work"}} {
+		if _, err := parseFindArgs(args); err == nil {
+			t.Errorf("parseFindArgs(%q) = nil error, want one", args)
+		}
+	}
+}
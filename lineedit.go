@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// Most recent prompt lines kept in the history file
+const historyLimit = 500
+
+// Every prompt reads through this one reader, so input that arrives ahead of
+// a prompt (pasted or piped) is still there when the prompt reads it
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// Ctrl-C at a prompt
+var errInterrupt = errors.New("interrupted")
+
+// The last text cut with Ctrl-K, Ctrl-U or Ctrl-W, for Ctrl-Y
+var killed []rune
+
+type lineEditor struct {
+	prompt   string
+	line     []rune
+	pos      int
+	history  []string
+	browsing int
+	draft    []rune
+	complete func(before string) []string
+}
+
+// A line for a prompt that takes commands: remembered in the journal's
+// history, and Tab completes with complete (given the text before the cursor)
+func promptLine(prompt string, complete func(before string) []string) (string, error) {
+	history := loadHistory()
+	line, err := editLine(prompt, history, complete)
+	if err == nil && strings.TrimSpace(line) != "" && (len(history) == 0 || history[len(history)-1] != line) {
+		if err := saveHistory(append(history, line)); err != nil {
+			fmt.Println("Error saving prompt history:", err)
+		}
+	}
+	return line, err
+}
+
+// A one-off answer, editable but not remembered
+func readAnswer(prompt string) (string, error) {
+	return editLine(prompt, nil, nil)
+}
+
+// Reads one line. On a terminal it is edited in raw mode; otherwise (piped
+// input) it is read as is. io.EOF once the input has run out
+func editLine(prompt string, history []string, complete func(string) []string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !stdoutIsTerminal() {
+		fmt.Print(prompt)
+		line, err := stdinReader.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, state)
+
+	e := &lineEditor{prompt: prompt, history: history, browsing: len(history), complete: complete}
+	e.refresh()
+	for {
+		r, _, err := stdinReader.ReadRune()
+		if err != nil {
+			fmt.Print("\r\n")
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Print("\r\n")
+			return string(e.line), nil
+		case 3: // Ctrl-C
+			fmt.Print("^C\r\n")
+			return "", errInterrupt
+		case 4: // Ctrl-D ends the input on an empty line, like a shell
+			if len(e.line) == 0 {
+				fmt.Print("\r\n")
+				return "", io.EOF
+			}
+			e.deleteForward()
+		case 1: // Ctrl-A
+			e.pos = 0
+		case 5: // Ctrl-E
+			e.pos = len(e.line)
+		case 2: // Ctrl-B
+			e.move(-1)
+		case 6: // Ctrl-F
+			e.move(1)
+		case 127, 8: // Backspace
+			if e.pos > 0 {
+				e.pos--
+				e.deleteForward()
+			}
+		case 11: // Ctrl-K
+			e.kill(e.pos, len(e.line))
+		case 21: // Ctrl-U
+			e.kill(0, e.pos)
+		case 23: // Ctrl-W
+			e.kill(e.wordStart(), e.pos)
+		case 25: // Ctrl-Y
+			e.insert(killed)
+		case 16: // Ctrl-P
+			e.historyStep(-1)
+		case 14: // Ctrl-N
+			e.historyStep(1)
+		case 12: // Ctrl-L
+			fmt.Print("\033[H\033[2J")
+		case '\t':
+			e.tabComplete()
+		case 27:
+			e.escape()
+		default:
+			if unicode.IsPrint(r) {
+				e.insert([]rune{r})
+			}
+		}
+		e.refresh()
+	}
+}
+
+// Redraws the prompt and line, then puts the cursor back where it belongs
+func (e *lineEditor) refresh() {
+	fmt.Print("\r", e.prompt, string(e.line), "\033[K")
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Printf("\033[%dD", back)
+	}
+}
+
+func (e *lineEditor) insert(runes []rune) {
+	line := append([]rune{}, e.line[:e.pos]...)
+	line = append(line, runes...)
+	e.line = append(line, e.line[e.pos:]...)
+	e.pos += len(runes)
+}
+
+func (e *lineEditor) deleteForward() {
+	if e.pos < len(e.line) {
+		e.line = append(e.line[:e.pos], e.line[e.pos+1:]...)
+	}
+}
+
+func (e *lineEditor) move(n int) {
+	e.pos = max(0, min(len(e.line), e.pos+n))
+}
+
+// Cuts line[from:to] into the kill buffer
+func (e *lineEditor) kill(from int, to int) {
+	if from >= to {
+		return
+	}
+	killed = append([]rune{}, e.line[from:to]...)
+	e.line = append(e.line[:from], e.line[to:]...)
+	e.pos = from
+}
+
+// Start of the word before the cursor, skipping the spaces after it
+func (e *lineEditor) wordStart() int {
+	i := e.pos
+	for i > 0 && e.line[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && e.line[i-1] != ' ' {
+		i--
+	}
+	return i
+}
+
+// End of the word after the cursor, skipping the spaces before it
+func (e *lineEditor) wordEnd() int {
+	i := e.pos
+	for i < len(e.line) && e.line[i] == ' ' {
+		i++
+	}
+	for i < len(e.line) && e.line[i] != ' ' {
+		i++
+	}
+	return i
+}
+
+// Up (-1) or down (1) through the history. Going past the newest line
+// brings back what was being typed
+func (e *lineEditor) historyStep(n int) {
+	next := e.browsing + n
+	if next < 0 || next > len(e.history) {
+		return
+	}
+	if e.browsing == len(e.history) {
+		e.draft = e.line
+	}
+	e.browsing = next
+	if next == len(e.history) {
+		e.line = e.draft
+	} else {
+		e.line = []rune(e.history[next])
+	}
+	e.pos = len(e.line)
+}
+
+// Arrow keys and friends, as xterm and most terminals send them. Alt+key
+// arrives as Escape then the key. Terminals send a sequence in one write, so
+// an Escape with nothing after it yet is a lone key press and does nothing
+func (e *lineEditor) escape() {
+	if stdinReader.Buffered() == 0 {
+		return
+	}
+	r, _, err := stdinReader.ReadRune()
+	if err != nil {
+		return
+	}
+	switch r {
+	case 'b':
+		e.pos = e.wordStart()
+		return
+	case 'f':
+		e.pos = e.wordEnd()
+		return
+	case 'd':
+		e.kill(e.pos, e.wordEnd())
+		return
+	case '[', 'O':
+	default:
+		return
+	}
+
+	// CSI: parameters, then a final byte from @ to ~
+	var params []rune
+	for {
+		r, _, err = stdinReader.ReadRune()
+		if err != nil {
+			return
+		}
+		if r >= '@' && r <= '~' {
+			break
+		}
+		params = append(params, r)
+	}
+	ctrl := strings.HasSuffix(string(params), ";5")
+	switch {
+	case r == 'A':
+		e.historyStep(-1)
+	case r == 'B':
+		e.historyStep(1)
+	case r == 'C' && ctrl:
+		e.pos = e.wordEnd()
+	case r == 'D' && ctrl:
+		e.pos = e.wordStart()
+	case r == 'C':
+		e.move(1)
+	case r == 'D':
+		e.move(-1)
+	case r == 'H', r == '~' && (string(params) == "1" || string(params) == "7"):
+		e.pos = 0
+	case r == 'F', r == '~' && (string(params) == "4" || string(params) == "8"):
+		e.pos = len(e.line)
+	case r == '~' && string(params) == "3":
+		e.deleteForward()
+	}
+}
+
+// Completes the word before the cursor as far as the candidates agree, and
+// lists them when that's no further
+func (e *lineEditor) tabComplete() {
+	if e.complete == nil {
+		return
+	}
+	start := e.pos
+	for start > 0 && e.line[start-1] != ' ' {
+		start--
+	}
+	word := string(e.line[start:e.pos])
+
+	var matches []string
+	for _, candidate := range e.complete(string(e.line[:e.pos])) {
+		if strings.HasPrefix(candidate, word) && !contains(matches, candidate) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		fmt.Print("\a")
+	case 1:
+		e.insert([]rune(strings.TrimPrefix(matches[0], word) + " "))
+	default:
+		prefix := []rune(matches[0])
+		for _, match := range matches[1:] {
+			for !strings.HasPrefix(match, string(prefix)) {
+				prefix = prefix[:len(prefix)-1]
+			}
+		}
+		if len(prefix) > len([]rune(word)) {
+			e.insert(prefix[len([]rune(word)):])
+			return
+		}
+		fmt.Print("\r\n", strings.Join(matches, "  "), "\r\n")
+	}
+}
+
+// Kept out of the save directory so it never ends up in the journal's git
+// history, one file per journal. Project journals are all "local", so each
+// is told apart by where its .journalz is
+func historyPath() string {
+	name := cfg.Journal
+	if name == "local" {
+		if path, ok := findLocalJournal(); ok {
+			sum := sha256.Sum256([]byte(path))
+			name = "local-" + hex.EncodeToString(sum[:])[:12]
+		}
+	}
+	return filepath.Join(dataHome(), "history", name)
+}
+
+func loadHistory() []string {
+	data, err := os.ReadFile(historyPath())
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			history = append(history, line)
+		}
+	}
+	return history
+}
+
+func saveHistory(history []string) error {
+	if len(history) > historyLimit {
+		history = history[len(history)-historyLimit:]
+	}
+	if err := os.MkdirAll(filepath.Dir(historyPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(historyPath(), []byte(strings.Join(history, "\n")+"\n"), 0600)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// [[Entry12]], [[my merge]] or [[Entry12|shown text]]
+var wikiLinkRegex = regexp.MustCompile(`\[\[([^\[\]|]+)(?:\|[^\[\]]*)?\]\]`)
+
+// Who links where, by entry name (file name without .md)
+type linkIndex struct {
+	Outgoing  map[string][]string
+	Backlinks map[string][]string
+	Broken    map[string][]string
+}
+
+func entryName(entry Entry) string {
+	return strings.TrimSuffix(entry.Info.Name(), ".md")
+}
+
+// Link targets in an Entry_ body, in order, without duplicates
+func parseLinks(body []string) []string {
+	var targets []string
+	for _, match := range wikiLinkRegex.FindAllStringSubmatch(strings.Join(body, "\n"), -1) {
+		target := strings.TrimSuffix(strings.TrimSpace(match[1]), ".md")
+		if target != "" && !contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// Entries and merges by name, the way findEntryPath resolves them: an entry
+// wins over a merge of the same name
+func entriesByName(entries []Entry) map[string]Entry {
+	byName := make(map[string]Entry)
+	for _, entry := range entries {
+		if entry.MergeOriginals == nil {
+			byName[entryName(entry)] = entry
+		}
+	}
+	for _, entry := range entries {
+		if _, taken := byName[entryName(entry)]; !taken {
+			byName[entryName(entry)] = entry
+		}
+	}
+	return byName
+}
+
+func buildLinkIndex(entries []Entry) linkIndex {
+	index := linkIndex{
+		Outgoing:  make(map[string][]string),
+		Backlinks: make(map[string][]string),
+		Broken:    make(map[string][]string),
+	}
+	byName := entriesByName(entries)
+	for _, entry := range entries {
+		body, err := getLines(entry.Path, "Entry_", "_Entry")
+		if err != nil {
+			continue
+		}
+		source := entryName(entry)
+		for _, target := range parseLinks(body) {
+			index.Outgoing[source] = append(index.Outgoing[source], target)
+			if _, ok := byName[target]; ok {
+				if target != source && !contains(index.Backlinks[target], source) {
+					index.Backlinks[target] = append(index.Backlinks[target], source)
+				}
+			} else {
+				index.Broken[source] = append(index.Broken[source], target)
+			}
+		}
+	}
+	return index
+}
+
+// links <entry>, or links -broken for every broken link in the journal
+func showLinks(args []string) {
+	linksCmd := flag.NewFlagSet("links", flag.ExitOnError)
+	broken := linksCmd.Bool("broken", false, "List every broken link in the journal")
+	linksCmd.Parse(args)
+
+	if !*broken && linksCmd.NArg() != 1 {
+		fmt.Println("Error: You must provide exactly one entry name, or -broken")
+		os.Exit(1)
+	}
+
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+	index := buildLinkIndex(entries)
+
+	if *broken {
+		var sources []string
+		for source := range index.Broken {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		if len(sources) == 0 {
+			fmt.Println("No broken links")
+			return
+		}
+		for _, source := range sources {
+			for _, target := range index.Broken[source] {
+				fmt.Println(source, "->", theme.Error+"[["+target+"]]"+theme.Reset)
+			}
+		}
+		os.Exit(1)
+	}
+
+	path, err := findEntryPath(linksCmd.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), ".md")
+
+	fmt.Println(theme.Title, "=========LINKS FROM "+name, theme.Reset)
+	if len(index.Outgoing[name]) == 0 {
+		fmt.Println("None")
+	}
+	for _, target := range index.Outgoing[name] {
+		if contains(index.Broken[name], target) {
+			fmt.Println(theme.Error+target+theme.Reset, "(broken, no entry or merge with that name)")
+		} else {
+			fmt.Println(target)
+		}
+	}
+
+	fmt.Println("")
+	fmt.Println(theme.Title, "=========BACKLINKS TO "+name, theme.Reset)
+	if len(index.Backlinks[name]) == 0 {
+		fmt.Println("None")
+	}
+	for _, source := range index.Backlinks[name] {
+		fmt.Println(source)
+	}
+}
+
+// " | 3 backlinks" for the results display, nothing when there are none
+func backlinkSummary(index linkIndex, entry Entry) string {
+	count := len(index.Backlinks[entryName(entry)])
+	switch count {
+	case 0:
+		return ""
+	case 1:
+		return " | 1 backlink"
+	}
+	return " | " + strconv.Itoa(count) + " backlinks"
+}
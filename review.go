@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const reviewFile = ".review.json"
+const reviewTag = "review"
+
+// SM-2 scheduling state for a single entry
+type reviewCard struct {
+	Ease        float64   `json:"ease"`
+	Interval    int       `json:"interval"`
+	Repetitions int       `json:"repetitions"`
+	Due         time.Time `json:"due"`
+}
+
+// Review state keyed by path relative to SAVEDIR
+func loadReviews() (map[string]reviewCard, error) {
+	cards := make(map[string]reviewCard)
+	data, err := os.ReadFile(filepath.Join(SAVEDIR, reviewFile))
+	if os.IsNotExist(err) {
+		return cards, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cards); err != nil {
+		return nil, fmt.Errorf("invalid review file: %w", err)
+	}
+	return cards, nil
+}
+func saveReviews(cards map[string]reviewCard) error {
+	data, err := json.MarshalIndent(cards, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(SAVEDIR, reviewFile), data, 0644)
+}
+
+// Applies a recall grade (0-5) to a card, SM-2 style
+func gradeCard(card reviewCard, grade int, today time.Time) reviewCard {
+	if card.Ease == 0 {
+		card.Ease = 2.5
+	}
+
+	// A failed recall starts the repetitions over but keeps the ease
+	if grade < 3 {
+		card.Repetitions = 0
+		card.Interval = 1
+	} else {
+		card.Repetitions++
+		switch card.Repetitions {
+		case 1:
+			card.Interval = 1
+		case 2:
+			card.Interval = 6
+		default:
+			card.Interval = int(math.Round(float64(card.Interval) * card.Ease))
+		}
+
+		q := float64(5 - grade)
+		card.Ease += 0.1 - q*(0.08+q*0.02)
+		if card.Ease < 1.3 {
+			card.Ease = 1.3
+		}
+	}
+	card.Due = today.AddDate(0, 0, card.Interval)
+	return card
+}
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+func reviewEntries(args []string) {
+	reviewCmd := flag.NewFlagSet("review", flag.ExitOnError)
+	all := reviewCmd.Bool("all", false, "Schedule every entry (default: only entries tagged '"+reviewTag+"')")
+	limit := reviewCmd.Int("n", 0, "Review at most this many entries (default: all due)")
+	reviewCmd.Parse(args)
+
+	searchTagSet := make(map[string]bool)
+	for _, tag := range reviewCmd.Args() {
+		searchTagSet[normalizeTag(tag)] = true
+	}
+	reviewTagSet := map[string]bool{reviewTag: true}
+
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+	cards, err := loadReviews()
+	if err != nil {
+		fmt.Println("Error reading review state:", err)
+		os.Exit(1)
+	}
+
+	// Entries never reviewed are due straight away
+	today := startOfDay(time.Now())
+	var due []Entry
+	for _, entry := range entries {
+		if !*all && !matchesTags(entry.Tags, reviewTagSet, false) {
+			continue
+		}
+		if len(searchTagSet) > 0 && !matchesTags(entry.Tags, searchTagSet, false) {
+			continue
+		}
+		if card, ok := cards[viewKey(entry.Path)]; ok && card.Due.After(today) {
+			continue
+		}
+		due = append(due, entry)
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return cards[viewKey(due[i].Path)].Due.Before(cards[viewKey(due[j].Path)].Due)
+	})
+	if *limit > 0 && len(due) > *limit {
+		due = due[:*limit]
+	}
+
+	if len(due) < 1 {
+		fmt.Println("Nothing due for review")
+		return
+	}
+
+	reviewed := 0
+	for i := 0; i < len(due); i++ {
+		entry := due[i]
+		key := viewKey(entry.Path)
+
+		clearTerminal()
+		fmt.Println(theme.Title, "=========REVIEW "+strconv.Itoa(i+1)+"/"+strconv.Itoa(len(due))+"===========================================================", theme.Reset)
+		date, err := getDate(entry.Path)
+		if err != nil {
+			fmt.Println("Error getting date from entry", err)
+		}
+		fmt.Println(theme.Emphasis, entry.Info.Name(), theme.Reset, " | Created: ", strings.TrimSpace(date))
+		fmt.Println("")
+		body, err := getLines(entry.Path, "Entry_", "_Entry")
+		if err != nil {
+			fmt.Println("Error reading body of entry at "+entry.Path, err)
+			continue
+		}
+		for _, line := range body {
+			fmt.Println("\t", theme.Text+line, theme.Reset)
+		}
+		fmt.Println(theme.Options, "=========OPTIONS================================================================", theme.Reset)
+		fmt.Print(theme.Option, "Grade your recall: ", theme.Reset, "0 (forgot) - 5 (perfect)\n")
+		fmt.Print(theme.Option, "[O]pen entry: ", theme.Reset, "o\n")
+		fmt.Print(theme.Option, "[S]kip: ", theme.Reset, "s\n")
+		fmt.Print(theme.Option, "[Q]uit: ", theme.Reset, "q\n")
+		answer, err := readAnswer("Your decision: ")
+		if err != nil {
+			break
+		}
+		input := strings.ToLower(strings.TrimSpace(answer))
+		switch input {
+		case "o":
+			openNvim(entry.Path, false)
+			// Grade it once it has been read
+			i--
+			continue
+		case "s":
+			continue
+		case "q":
+			fmt.Println("Reviewed", reviewed, "entries")
+			return
+		}
+
+		grade, err := strconv.Atoi(input)
+		if err != nil || grade < 0 || grade > 5 {
+			i--
+			continue
+		}
+		cards[key] = gradeCard(cards[key], grade, today)
+		if err := saveReviews(cards); err != nil {
+			fmt.Println("Error saving review state:", err)
+			os.Exit(1)
+		}
+		reviewed++
+	}
+	fmt.Println("Reviewed", reviewed, "entries")
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGradeCard(t *testing.T) {
+	today := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		card  reviewCard
+		grade int
+		want  reviewCard
+	}{
+		{"first recall", reviewCard{}, 4, reviewCard{Ease: 2.5, Interval: 1, Repetitions: 1}},
+		{"second recall", reviewCard{Ease: 2.5, Interval: 1, Repetitions: 1}, 5, reviewCard{Ease: 2.6, Interval: 6, Repetitions: 2}},
+		{"later recall", reviewCard{Ease: 2.5, Interval: 6, Repetitions: 2}, 3, reviewCard{Ease: 2.36, Interval: 15, Repetitions: 3}},
+		{"failure keeps the ease", reviewCard{Ease: 2.2, Interval: 15, Repetitions: 3}, 1, reviewCard{Ease: 2.2, Interval: 1, Repetitions: 0}},
+		{"ease floor", reviewCard{Ease: 1.3, Interval: 6, Repetitions: 2}, 3, reviewCard{Ease: 1.3, Interval: 8, Repetitions: 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := gradeCard(test.card, test.grade, today)
+			if math.Abs(got.Ease-test.want.Ease) > 1e-9 || got.Interval != test.want.Interval || got.Repetitions != test.want.Repetitions {
+				t.Errorf("gradeCard(%+v, %d) = %+v, want %+v", test.card, test.grade, got, test.want)
+			}
+			if want := today.AddDate(0, 0, test.want.Interval); !got.Due.Equal(want) {
+				t.Errorf("due %v, want %v", got.Due, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var savedSearchesPath string = filepath.Join(configHome(), "searches.cfg")
+
+// A find query kept by name. Flags are kept apart from the tags so @name
+// can be used anywhere in a find, the flags still go before the tags
+type savedSearch struct {
+	Name  string
+	Flags []string
+	Tags  []string
+}
+
+func (s savedSearch) String() string {
+	return strings.Join(append(append([]string{}, s.Flags...), s.Tags...), " ")
+}
+
+// Reads the saved searches. Each line is name=flags tags...
+// e.g. taxes=-i -d -from=30d finance tax receipts
+// Flags are always written as one word, so anything starting with - is one
+func loadSavedSearches() ([]savedSearch, error) {
+	file, err := os.Open(savedSearchesPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var searches []savedSearch
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid saved search line: %s", line)
+		}
+		search := savedSearch{Name: strings.TrimSpace(parts[0])}
+		for _, word := range strings.Fields(parts[1]) {
+			if strings.HasPrefix(word, "-") {
+				search.Flags = append(search.Flags, word)
+			} else {
+				search.Tags = append(search.Tags, word)
+			}
+		}
+		searches = append(searches, search)
+	}
+	return searches, scanner.Err()
+}
+
+func writeSavedSearches(searches []savedSearch) error {
+	if err := os.MkdirAll(filepath.Dir(savedSearchesPath), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("# Saved find queries, run with journalz_ro find @name\n")
+	for _, search := range searches {
+		b.WriteString(search.Name + "=" + search.String() + "\n")
+	}
+	return os.WriteFile(savedSearchesPath, []byte(b.String()), 0644)
+}
+
+// Adds the search, replacing one with the same name
+func saveSearch(search savedSearch) error {
+	if search.Name == "" || strings.ContainsAny(search.Name, "=@# \t") {
+		return fmt.Errorf("invalid name %q, use letters, numbers, - or _", search.Name)
+	}
+	for _, tag := range search.Tags {
+		if strings.ContainsAny(tag, " \t") {
+			return fmt.Errorf("tags with spaces can't be saved: %q", tag)
+		}
+	}
+	searches, err := loadSavedSearches()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range searches {
+		if searches[i].Name == search.Name {
+			searches[i] = search
+			replaced = true
+		}
+	}
+	if !replaced {
+		searches = append(searches, search)
+	}
+	return writeSavedSearches(searches)
+}
+
+// Replaces each @name with the saved query: its flags go first, so they
+// still parse as flags (and flags typed after them win), its tags in place
+func expandSavedSearches(args []string) ([]string, error) {
+	hasSaved := false
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "@") {
+			hasSaved = true
+		}
+	}
+	if !hasSaved {
+		return args, nil
+	}
+
+	searches, err := loadSavedSearches()
+	if err != nil {
+		return nil, err
+	}
+	var flags []string
+	var rest []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			rest = append(rest, arg)
+			continue
+		}
+		found := false
+		for _, search := range searches {
+			if search.Name == arg[1:] {
+				flags = append(flags, search.Flags...)
+				rest = append(rest, search.Tags...)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no saved search named %s (see journalz_ro saved)", arg[1:])
+		}
+	}
+	return append(flags, rest...), nil
+}
+
+func savedSearchNames() []string {
+	searches, err := loadSavedSearches()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, search := range searches {
+		names = append(names, search.Name)
+	}
+	return names
+}
+
+// The flags find was given, one word each, for saving. -save itself isn't kept
+func setFlags(flags *flag.FlagSet) []string {
+	var words []string
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "save" {
+			return
+		}
+		if getter, ok := f.Value.(flag.Getter); ok {
+			if on, isBool := getter.Get().(bool); isBool {
+				if on {
+					words = append(words, "-"+f.Name)
+				}
+				return
+			}
+		}
+		words = append(words, "-"+f.Name+"="+f.Value.String())
+	})
+	return words
+}
+
+// MM/DD/YYYY, YYYY-MM-DD, or a number of days before today like 30d, so a
+// saved search can keep meaning "the last month"
+func parseDateFlag(value string, today time.Time) (time.Time, error) {
+	for _, layout := range []string{"01/02/2006", "2006-01-02"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && strings.HasSuffix(value, "d") && days >= 0 {
+		return time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -days), nil
+	}
+	return time.Time{}, fmt.Errorf("expected MM/DD/YYYY, YYYY-MM-DD or a number of days like 30d, got %q", value)
+}
+
+// saved, or saved -rm name
+func listSavedSearches(args []string) {
+	savedCmd := flag.NewFlagSet("saved", flag.ExitOnError)
+	remove := savedCmd.String("rm", "", "Delete the saved search with this name")
+	savedCmd.Parse(args)
+
+	searches, err := loadSavedSearches()
+	if err != nil {
+		fmt.Println("Error reading saved searches:", err)
+		os.Exit(1)
+	}
+
+	if *remove != "" {
+		var kept []savedSearch
+		for _, search := range searches {
+			if search.Name != *remove {
+				kept = append(kept, search)
+			}
+		}
+		if len(kept) == len(searches) {
+			fmt.Println("No saved search named " + *remove)
+			os.Exit(1)
+		}
+		if err := writeSavedSearches(kept); err != nil {
+			fmt.Println("Error writing saved searches:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Deleted @" + *remove)
+		return
+	}
+
+	if len(searches) == 0 {
+		fmt.Println("No saved searches. Save one with journalz_ro find ... -save name")
+		return
+	}
+	for _, search := range searches {
+		fmt.Println(theme.Tag+"@"+search.Name+theme.Reset, " find", search.String())
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const selectionHelp = "use numbers, ranges like 2-5 or 10-, * for all and ^ to leave some out, e.g. 1-5,8 ^3"
+
+// Parses a selection of numbered entries out of count, e.g. 1-5,8 ^3 or *.
+// Parts are separated by spaces or commas: N, N-M, N- (to the last), -M
+// (from the first), * (all), and any of those after ^ to leave them out.
+// With only ^ parts, everything else is selected. Returns zero-based
+// indexes in ascending order, or an error naming the first part that
+// doesn't fit, before anything is done
+func parseSelection(args []string, count int) ([]int, error) {
+	var parts []string
+	for _, arg := range args {
+		for _, part := range strings.Split(arg, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("nothing selected, %s", selectionHelp)
+	}
+
+	selected := make(map[int]bool)
+	excluded := make(map[int]bool)
+	onlyExclusions := true
+	for _, part := range parts {
+		target := selected
+		if strings.HasPrefix(part, "^") {
+			target = excluded
+			part = part[1:]
+		} else {
+			onlyExclusions = false
+		}
+		from, to, err := parseSelectionRange(part, count)
+		if err != nil {
+			return nil, err
+		}
+		for i := from; i <= to; i++ {
+			target[i] = true
+		}
+	}
+	if onlyExclusions {
+		for i := 1; i <= count; i++ {
+			selected[i] = true
+		}
+	}
+
+	var indexes []int
+	for i := range selected {
+		if !excluded[i] {
+			indexes = append(indexes, i-1)
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("everything selected was left out again with ^")
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// One part of a selection as a one-based, inclusive range
+func parseSelectionRange(part string, count int) (int, int, error) {
+	if part == "*" {
+		if count == 0 {
+			return 0, 0, fmt.Errorf("there is nothing to select")
+		}
+		return 1, count, nil
+	}
+
+	from, to := part, part
+	if i := strings.Index(part, "-"); i != -1 {
+		from, to = part[:i], part[i+1:]
+		if from == "" {
+			from = "1"
+		}
+		if to == "" {
+			to = strconv.Itoa(count)
+		}
+	}
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a number, range or *, %s", part, selectionHelp)
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a number, range or *, %s", part, selectionHelp)
+	}
+	if start < 1 || start > count || end > count {
+		return 0, 0, fmt.Errorf("%s is out of range, pick from 1 to %d", part, count)
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("%s goes backwards, write ranges low to high", part)
+	}
+	return start, end, nil
+}
+
+// The entries at the selected indexes
+func selectEntries(entries []Entry, indexes []int) []Entry {
+	var selection []Entry
+	for _, i := range indexes {
+		selection = append(selection, entries[i])
+	}
+	return selection
+}
+
+// The entries left once the selected indexes are taken out
+func withoutSelected(entries []Entry, indexes []int) []Entry {
+	var kept []Entry
+	for i, entry := range entries {
+		if !containsIndex(indexes, i) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+func containsIndex(indexes []int, target int) bool {
+	for _, i := range indexes {
+		if i == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Entry file names, for messages
+func fileNames(entries []Entry) []string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Info.Name())
+	}
+	return names
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []int
+	}{
+		{"single", []string{"3"}, []int{2}},
+		{"list and range", []string{"1-3,5"}, []int{0, 1, 2, 4}},
+		{"spaces and commas", []string{"5,", "1", " 2 "}, []int{0, 1, 4}},
+		{"all", []string{"*"}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"to the last", []string{"8-"}, []int{7, 8, 9}},
+		{"from the first", []string{"-3"}, []int{0, 1, 2}},
+		{"exclusion", []string{"1-5,8", "^3"}, []int{0, 1, 3, 4, 7}},
+		{"only exclusions", []string{"^2-9"}, []int{0, 9}},
+		{"excluded range", []string{"*", "^-8"}, []int{8, 9}},
+		{"overlapping", []string{"2-4", "3-5", "4"}, []int{1, 2, 3, 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseSelection(test.args, 10)
+			if err != nil {
+				t.Fatalf("parseSelection(%q) error: %v", test.args, err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("parseSelection(%q) = %v, want %v", test.args, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSelectionErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		count int
+	}{
+		{"nothing", []string{}, 10},
+		{"blank", []string{"", " , "}, 10},
+		{"zero", []string{"0"}, 10},
+		{"out of range", []string{"11"}, 10},
+		{"range out of range", []string{"8-12"}, 10},
+		{"backwards", []string{"5-2"}, 10},
+		{"not a number", []string{"two"}, 10},
+		{"bad range", []string{"1-x"}, 10},
+		{"everything excluded", []string{"1-3", "^*"}, 10},
+		{"all of nothing", []string{"*"}, 0},
+		{"exclusion out of range", []string{"^11"}, 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := parseSelection(test.args, test.count); err == nil {
+				t.Errorf("parseSelection(%q, %d) = %v, want an error", test.args, test.count, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// The journal lives in globals and plain files, so requests take turns
+var serveMutex sync.Mutex
+
+// What the API returns for an entry or merge
+type apiEntry struct {
+	Name      string   `json:"name"`
+	Merge     bool     `json:"merge"`
+	Date      string   `json:"date"`
+	Modified  string   `json:"modified"`
+	Tags      []string `json:"tags"`
+	Originals []string `json:"originals,omitempty"`
+	Preview   []string `json:"preview,omitempty"`
+	Body      []string `json:"body,omitempty"`
+	HTML      string   `json:"html,omitempty"`
+	Encrypted bool     `json:"encrypted"`
+}
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// Largest request body the API reads, far more than any entry needs
+const maxRequestBody = 1 << 20
+
+func serve(args []string) {
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := serveCmd.String("addr", "localhost:8080", "Address to listen on, e.g. 0.0.0.0:8080 for the whole LAN")
+	token := serveCmd.String("token", os.Getenv("JOURNALZ_TOKEN"), "Require this token as 'Authorization: Bearer <token>' or ?token= (default: $JOURNALZ_TOKEN, or a new random one)")
+	serveCmd.Parse(args)
+
+	// Requests can't wait on a passphrase typed into the server's terminal,
+	// encrypted entries are served only with JOURNALZ_PASSPHRASE set
+	askPassphrase = false
+
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		fmt.Println("Error loading web UI", err)
+		os.Exit(1)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/entries", apiListEntries)
+	mux.HandleFunc("POST /api/entries", apiCreateEntry)
+	mux.HandleFunc("GET /api/entries/{name}", apiGetEntry)
+	mux.HandleFunc("PUT /api/entries/{name}/tags", apiUpdateTags)
+	mux.HandleFunc("DELETE /api/entries/{name}", apiDeleteEntry)
+	mux.HandleFunc("POST /api/merges", apiMerge)
+	mux.HandleFunc("GET /api/tags", apiListTags)
+	mux.Handle("GET /", http.FileServerFS(static))
+
+	// Even on localhost any web page could otherwise call the API, so there
+	// is always a token
+	if *token == "" {
+		*token, err = randomToken()
+		if err != nil {
+			fmt.Println("Error generating a token", err)
+			os.Exit(1)
+		}
+	}
+	handler := guardRequests(*addr, requireToken(*token, mux))
+
+	fmt.Println("Serving", SAVEDIR, "on http://"+*addr+"/?token="+url.QueryEscape(*token))
+	server := &http.Server{Addr: *addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	if err := server.ListenAndServe(); err != nil {
+		fmt.Println("Error serving", err)
+		os.Exit(1)
+	}
+}
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if given == "" {
+			given = r.URL.Query().Get("token")
+		}
+		// The UI itself is public, it asks for the token before calling the API
+		if strings.HasPrefix(r.URL.Path, "/api/") && subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, apiError{"missing or wrong token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Refuses requests sent from other sites (Origin not this server) and, when
+// listening on loopback only, any Host but the loopback names, so a DNS
+// rebinding page can't pass for this server. Bodies are capped too
+func guardRequests(addr string, next http.Handler) http.Handler {
+	host, port, err := net.SplitHostPort(addr)
+	loopback := err == nil && (host == "localhost" || net.ParseIP(host).IsLoopback())
+	allowedHosts := map[string]bool{
+		net.JoinHostPort("localhost", port): true,
+		net.JoinHostPort("127.0.0.1", port): true,
+		net.JoinHostPort("::1", port):       true,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loopback && !allowedHosts[strings.ToLower(r.Host)] {
+			writeJSON(w, http.StatusForbidden, apiError{"unexpected Host " + r.Host})
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			parsed, err := url.Parse(origin)
+			if err != nil || !strings.EqualFold(parsed.Host, r.Host) {
+				writeJSON(w, http.StatusForbidden, apiError{"cross-origin requests are not allowed"})
+				return
+			}
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		next.ServeHTTP(w, r)
+	})
+}
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+func toAPIEntry(entry Entry, full bool) (apiEntry, error) {
+	body, err := getLines(entry.Path, "Entry_", "_Entry")
+	if err != nil {
+		return apiEntry{}, err
+	}
+	date, err := getDate(entry.Path)
+	if err != nil {
+		return apiEntry{}, err
+	}
+
+	result := apiEntry{
+		Name:      strings.TrimSuffix(entry.Info.Name(), ".md"),
+		Merge:     entry.MergeOriginals != nil,
+		Date:      strings.TrimSpace(date),
+		Modified:  entry.Info.ModTime().Format(time.RFC3339),
+		Tags:      normalizeTags(entry.Tags),
+		Originals: entry.MergeOriginals,
+		Encrypted: isEncryptedFile(entry.Path),
+	}
+	if full {
+		result.Body = body
+		result.HTML = markdownToHTML(body)
+	} else {
+		result.Preview = body[:min(len(body), 5)]
+	}
+	return result, nil
+}
+
+// Looks an entry up by name, the same way the log command does
+func entryByName(name string) (Entry, error) {
+	path, err := findEntryPath(name)
+	if err != nil {
+		return Entry{}, err
+	}
+	return entryAt(path)
+}
+
+// The entry or merge at path, with its tags and originals
+func entryAt(path string) (Entry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{Path: path, Info: info}
+	if entry.Tags, err = getLines(path, "Tags_", "_Tags"); err != nil {
+		return Entry{}, err
+	}
+	if filepath.Dir(path) == filepath.Clean(MERGE_DIR) {
+		if entry.MergeOriginals, err = getLines(path, "Originals_", "_Originals"); err != nil {
+			return Entry{}, err
+		}
+	}
+	return entry, nil
+}
+
+// GET /api/entries?tags=a,b&inclusive=true&originals=true&sort=asc|desc&q=text
+func apiListEntries(w http.ResponseWriter, r *http.Request) {
+	serveMutex.Lock()
+	defer serveMutex.Unlock()
+
+	query := r.URL.Query()
+	searchTagSet := make(map[string]bool)
+	for _, tag := range strings.Split(query.Get("tags"), ",") {
+		if tag = normalizeTag(tag); tag != "" {
+			searchTagSet[tag] = true
+		}
+	}
+	opts := journalSearchOptions(query.Get("inclusive") == "true", query.Get("originals") == "true", query.Get("sort") == "asc", query.Get("sort") == "desc")
+	opts.Text = query.Get("q")
+
+	entries, err := loadEntries()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	results := []apiEntry{}
+	for _, entry := range searchEntries(entries, searchTagSet, opts) {
+		result, err := toAPIEntry(entry, false)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+			return
+		}
+		results = append(results, result)
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// GET /api/entries/{name}
+func apiGetEntry(w http.ResponseWriter, r *http.Request) {
+	serveMutex.Lock()
+	defer serveMutex.Unlock()
+
+	entry, err := entryByName(r.PathValue("name"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, apiError{err.Error()})
+		return
+	}
+	result, err := toAPIEntry(entry, true)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /api/entries {"body": "...", "tags": ["a"]}
+func apiCreateEntry(w http.ResponseWriter, r *http.Request) {
+	serveMutex.Lock()
+	defer serveMutex.Unlock()
+
+	var request struct {
+		Body string   `json:"body"`
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{"invalid JSON: " + err.Error()})
+		return
+	}
+
+	template, err := readTemplate(TEMPLATE)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	tags := uniqueTags(request.Tags)
+	content, err := fillTemplate(template, importItem{Date: time.Now(), Body: strings.Split(request.Body, "\n"), Tags: tags})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	name, err := nextEntryName()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	if err := writeEntryFile(filepath.Join(SAVEDIR, name), content, shouldEncrypt(tags)); err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	gitCommit(describeEntry("Add", name, tags))
+
+	apiRespondEntry(w, http.StatusCreated, filepath.Join(SAVEDIR, name))
+}
+
+// PUT /api/entries/{name}/tags {"tags": ["a", "b"]}
+func apiUpdateTags(w http.ResponseWriter, r *http.Request) {
+	serveMutex.Lock()
+	defer serveMutex.Unlock()
+
+	var request struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{"invalid JSON: " + err.Error()})
+		return
+	}
+	entry, err := entryByName(r.PathValue("name"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, apiError{err.Error()})
+		return
+	}
+
+	tags := uniqueTags(request.Tags)
+	data, err := readEntryFile(entry.Path)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	data, err = replaceSection(data, "Tags_", "_Tags", tags)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{err.Error()})
+		return
+	}
+	if err := writeEntryFile(entry.Path, data, isEncryptedFile(entry.Path) || shouldEncrypt(tags)); err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	gitCommit(describeEntry("Retag", entry.Info.Name(), tags))
+
+	apiRespondEntry(w, http.StatusOK, entry.Path)
+}
+
+// DELETE /api/entries/{name} moves the file to .trash in SAVEDIR
+func apiDeleteEntry(w http.ResponseWriter, r *http.Request) {
+	serveMutex.Lock()
+	defer serveMutex.Unlock()
+
+	entry, err := entryByName(r.PathValue("name"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, apiError{err.Error()})
+		return
+	}
+	trashPath, err := moveToTrash(entry.Path)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+	gitCommit("Delete " + entry.Info.Name())
+
+	writeJSON(w, http.StatusOK, map[string]string{"trashed": filepath.Base(trashPath)})
+}
+
+// Trashed files get a timestamp so deleting the same name twice keeps both
+func moveToTrash(path string) (string, error) {
+	trashDir := filepath.Join(SAVEDIR, ".trash")
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), ".md")
+	trashPath := filepath.Join(trashDir, name+"."+time.Now().Format("20060102-150405")+".md")
+	return trashPath, os.Rename(path, trashPath)
+}
+
+// POST /api/merges {"name": "...", "entries": ["Entry1", "Entry2"]}
+func apiMerge(w http.ResponseWriter, r *http.Request) {
+	serveMutex.Lock()
+	defer serveMutex.Unlock()
+
+	var request struct {
+		Name    string   `json:"name"`
+		Entries []string `json:"entries"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{"invalid JSON: " + err.Error()})
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" || strings.ContainsAny(request.Name, `/\`) || strings.HasPrefix(request.Name, ".") {
+		writeJSON(w, http.StatusBadRequest, apiError{"a merge needs a name without slashes or a leading dot"})
+		return
+	}
+	if len(request.Entries) < 2 {
+		writeJSON(w, http.StatusBadRequest, apiError{"a merge needs at least two entries"})
+		return
+	}
+	// An entry of the same name would shadow the merge wherever names are resolved
+	if fileExists(filepath.Join(MERGE_DIR, request.Name+".md")) || fileExists(filepath.Join(SAVEDIR, request.Name+".md")) {
+		writeJSON(w, http.StatusConflict, apiError{"an entry or merge named " + request.Name + " already exists"})
+		return
+	}
+
+	mergeList = nil
+	for _, name := range request.Entries {
+		entry, err := entryByName(name)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, apiError{err.Error()})
+			return
+		}
+		mergeList = append(mergeList, entry)
+	}
+	_, err := makeMergeEntry(request.Name)
+	mergeList = nil
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{err.Error()})
+		return
+	}
+
+	apiRespondEntry(w, http.StatusCreated, filepath.Join(MERGE_DIR, request.Name+".md"))
+}
+
+// GET /api/tags
+func apiListTags(w http.ResponseWriter, r *http.Request) {
+	serveMutex.Lock()
+	defer serveMutex.Unlock()
+
+	writeJSON(w, http.StatusOK, tagNames())
+}
+func apiRespondEntry(w http.ResponseWriter, status int, path string) {
+	entry, err := entryAt(path)
+	if err == nil {
+		var result apiEntry
+		if result, err = toAPIEntry(entry, true); err == nil {
+			writeJSON(w, status, result)
+			return
+		}
+	}
+	writeJSON(w, http.StatusInternalServerError, apiError{"saved, but could not be read back: " + err.Error()})
+}
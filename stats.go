@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type streak struct {
+	Days  int    `json:"days"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+type journalStats struct {
+	Entries       int        `json:"entries"`
+	Merges        int        `json:"merges"`
+	Undated       int        `json:"undated"`
+	FirstEntry    string     `json:"first_entry,omitempty"`
+	LastEntry     string     `json:"last_entry,omitempty"`
+	DaysWritten   int        `json:"days_written"`
+	PerDay        float64    `json:"per_day"`
+	PerWeek       float64    `json:"per_week"`
+	PerMonth      float64    `json:"per_month"`
+	CurrentStreak streak     `json:"current_streak"`
+	LongestStreak streak     `json:"longest_streak"`
+	Words         int        `json:"words"`
+	WordsPerEntry float64    `json:"words_per_entry"`
+	TopTagsDays   int        `json:"top_tags_days"`
+	TopTags       []tagCount `json:"top_tags"`
+	Merged        int        `json:"merged"`
+	MergedPercent float64    `json:"merged_percent"`
+}
+
+// Counts, rates, streaks and tags, all from the date header in each entry
+// rather than file times, which imports and copies don't preserve
+func computeStats(entries []Entry, today time.Time, tagDays int, top int) journalStats {
+	var stats journalStats
+	stats.TopTagsDays = tagDays
+	// Entry dates parse as UTC midnights, so compare against one
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	merged := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.MergeOriginals != nil {
+			stats.Merges++
+			for _, original := range entry.MergeOriginals {
+				if name := strings.TrimSpace(original); name != "" {
+					merged[name] = true
+				}
+			}
+		}
+	}
+
+	days := make(map[time.Time]int)
+	tagCounts := make(map[string]int)
+	var first, last time.Time
+	for _, entry := range entries {
+		if entry.MergeOriginals != nil {
+			continue
+		}
+		stats.Entries++
+		if merged[entry.Info.Name()] {
+			stats.Merged++
+		}
+
+		body, err := getLines(entry.Path, "Entry_", "_Entry")
+		if err == nil {
+			stats.Words += len(strings.Fields(strings.Join(body, "\n")))
+		}
+
+		date, err := entryDate(entry)
+		if err != nil {
+			stats.Undated++
+			continue
+		}
+		days[date]++
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+		if date.After(last) {
+			last = date
+		}
+		if tagDays <= 0 || !date.Before(today.AddDate(0, 0, -tagDays+1)) {
+			for _, tag := range normalizeTags(entry.Tags) {
+				tagCounts[tag]++
+			}
+		}
+	}
+
+	if stats.Entries > 0 {
+		stats.WordsPerEntry = float64(stats.Words) / float64(stats.Entries)
+		stats.MergedPercent = 100 * float64(stats.Merged) / float64(stats.Entries)
+	}
+
+	stats.DaysWritten = len(days)
+	if !first.IsZero() {
+		stats.FirstEntry = first.Format("01/02/2006")
+		stats.LastEntry = last.Format("01/02/2006")
+		end := today
+		if last.After(end) {
+			end = last
+		}
+		span := float64(int(end.Sub(first).Hours()/24) + 1)
+		dated := float64(stats.Entries - stats.Undated)
+		stats.PerDay = dated / span
+		stats.PerWeek = stats.PerDay * 7
+		stats.PerMonth = stats.PerDay * 365.25 / 12
+	}
+
+	stats.CurrentStreak, stats.LongestStreak = streaks(days, today)
+
+	for tag, count := range tagCounts {
+		stats.TopTags = append(stats.TopTags, tagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(stats.TopTags, func(i, j int) bool {
+		if stats.TopTags[i].Count != stats.TopTags[j].Count {
+			return stats.TopTags[i].Count > stats.TopTags[j].Count
+		}
+		return stats.TopTags[i].Tag < stats.TopTags[j].Tag
+	})
+	if len(stats.TopTags) > top {
+		stats.TopTags = stats.TopTags[:top]
+	}
+	return stats
+}
+
+// Runs of consecutive days with at least one entry. The current streak
+// survives a day with nothing written yet, so it ends today or yesterday
+func streaks(days map[time.Time]int, today time.Time) (streak, streak) {
+	var dates []time.Time
+	for date := range days {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	var current, longest, run streak
+	var runStart, previous time.Time
+	for _, date := range dates {
+		if !previous.IsZero() && date.Equal(previous.AddDate(0, 0, 1)) {
+			run.Days++
+		} else {
+			run.Days = 1
+			runStart = date
+		}
+		previous = date
+		run.Start = runStart.Format("01/02/2006")
+		run.End = date.Format("01/02/2006")
+		if run.Days > longest.Days {
+			longest = run
+		}
+	}
+	if !previous.IsZero() && !previous.Before(today.AddDate(0, 0, -1)) {
+		current = run
+	}
+	return current, longest
+}
+
+func showStats(args []string) {
+	statsCmd := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := statsCmd.Bool("json", false, "Print the statistics as JSON")
+	tagDays := statsCmd.Int("days", 30, "Count top tags over the last N days, 0 for all time")
+	top := statsCmd.Int("top", 10, "Number of top tags to show")
+	statsCmd.Parse(args)
+
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+	stats := computeStats(entries, time.Now(), *tagDays, *top)
+
+	if *asJSON {
+		out, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			fmt.Println("Error encoding statistics:", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	row := func(label string, value string) {
+		fmt.Printf("%s%-18s%s %s\n", theme.Heading, label, theme.Reset, value)
+	}
+	fmt.Println(theme.Title, "=========STATS==================================================================", theme.Reset)
+	row("Entries", strconv.Itoa(stats.Entries))
+	row("Merges", strconv.Itoa(stats.Merges))
+	if stats.Undated > 0 {
+		row("Without a date", strconv.Itoa(stats.Undated))
+	}
+	if stats.FirstEntry != "" {
+		row("Written", stats.FirstEntry+" - "+stats.LastEntry+" on "+strconv.Itoa(stats.DaysWritten)+" days")
+	}
+	row("Per day", fmt.Sprintf("%.2f", stats.PerDay))
+	row("Per week", fmt.Sprintf("%.1f", stats.PerWeek))
+	row("Per month", fmt.Sprintf("%.1f", stats.PerMonth))
+	row("Current streak", formatStreak(stats.CurrentStreak))
+	row("Longest streak", formatStreak(stats.LongestStreak))
+	row("Words", strconv.Itoa(stats.Words))
+	row("Words per entry", fmt.Sprintf("%.0f", stats.WordsPerEntry))
+	row("Merged", fmt.Sprintf("%d (%.0f%%)", stats.Merged, stats.MergedPercent))
+
+	period := "all time"
+	if stats.TopTagsDays > 0 {
+		period = "last " + strconv.Itoa(stats.TopTagsDays) + " days"
+	}
+	fmt.Println("")
+	fmt.Println(theme.Title, "=========TOP TAGS ("+period+")", theme.Reset)
+	if len(stats.TopTags) == 0 {
+		fmt.Println("No tagged entries")
+	}
+	for _, tag := range stats.TopTags {
+		fmt.Println(theme.Tag+tag.Tag+theme.Reset, "("+strconv.Itoa(tag.Count)+")")
+	}
+}
+
+func formatStreak(s streak) string {
+	if s.Days == 0 {
+		return "0 days"
+	}
+	days := strconv.Itoa(s.Days) + " days"
+	if s.Days == 1 {
+		days = "1 day"
+	}
+	return days + " (" + s.Start + " - " + s.End + ")"
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type tagSuggestion struct {
+	Tag   string
+	Score float64
+}
+
+// Tags whose entries read like this body. Each tag is the (normalised) sum
+// of the TF-IDF vectors of the entries carrying it, and is scored by its
+// cosine similarity with the body. The entry itself is left out of training
+func suggestTags(path string, body []string, existing []string, entries []Entry, limit int) []tagSuggestion {
+	docs := [][]string{tokenize(strings.Join(body, "\n"))}
+	var tagged []Entry
+	for _, entry := range entries {
+		if filepath.Clean(entry.Path) == filepath.Clean(path) || entry.MergeOriginals != nil || len(normalizeTags(entry.Tags)) == 0 {
+			continue
+		}
+		entryBody, err := getLines(entry.Path, "Entry_", "_Entry")
+		if err != nil {
+			continue
+		}
+		docs = append(docs, tokenize(strings.Join(entryBody, "\n")))
+		tagged = append(tagged, entry)
+	}
+	if len(docs[0]) == 0 {
+		return nil
+	}
+	vectors := tfidfVectors(docs)
+
+	centroids := make(map[string]map[string]float64)
+	for i, entry := range tagged {
+		for _, tag := range normalizeTags(entry.Tags) {
+			if centroids[tag] == nil {
+				centroids[tag] = make(map[string]float64)
+			}
+			for word, weight := range vectors[i+1] {
+				centroids[tag][word] += weight
+			}
+		}
+	}
+
+	has := make(map[string]bool)
+	for _, tag := range normalizeTags(existing) {
+		has[tag] = true
+	}
+	var suggestions []tagSuggestion
+	for tag, centroid := range centroids {
+		if has[tag] {
+			continue
+		}
+		norm := 0.0
+		for _, weight := range centroid {
+			norm += weight * weight
+		}
+		if norm == 0 {
+			continue
+		}
+		score := cosine(vectors[0], centroid) / math.Sqrt(norm)
+		// Below this it's mostly shared filler words
+		if score >= 0.1 {
+			suggestions = append(suggestions, tagSuggestion{Tag: tag, Score: score})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// Rewrites only the Tags_ section, keeping the entry encrypted if it was
+// (or if the new tags call for it)
+func setEntryTags(path string, tags []string) error {
+	data, err := readEntryFile(path)
+	if err != nil {
+		return err
+	}
+	data, err = replaceSection(data, "Tags_", "_Tags", tags)
+	if err != nil {
+		return err
+	}
+	return writeEntryFile(path, data, isEncryptedFile(path) || shouldEncrypt(tags))
+}
+
+// Tags_ lines with tag added after the last tag, unless the entry already
+// has it (or an alias of it). The lines already there stay as they are,
+// blank ones included
+func addTagLine(lines []string, tag string) ([]string, bool) {
+	at := 0
+	for i, line := range lines {
+		if normalizeTag(line) == normalizeTag(tag) {
+			return lines, false
+		}
+		if strings.TrimSpace(line) != "" {
+			at = i + 1
+		}
+	}
+	tags := append([]string{}, lines[:at]...)
+	tags = append(tags, tag)
+	return append(tags, lines[at:]...), true
+}
+
+// Asks about each suggestion in turn and adds the accepted ones to Tags_,
+// leaving the tags already there as they were. Returns the tags the entry
+// ends up with and whether any were added
+func offerTagSuggestions(path string) ([]string, bool, error) {
+	entries, err := loadEntries()
+	if err != nil {
+		return nil, false, err
+	}
+	body, err := getLines(path, "Entry_", "_Entry")
+	if err != nil {
+		return nil, false, err
+	}
+	existing, err := getLines(path, "Tags_", "_Tags")
+	if err != nil {
+		return nil, false, err
+	}
+
+	suggestions := suggestTags(path, body, existing, entries, 5)
+	if len(suggestions) == 0 {
+		fmt.Println("No tag suggestions for " + filepath.Base(path))
+		return existing, false, nil
+	}
+
+	var accepted []string
+	for _, suggestion := range suggestions {
+		answer, err := readAnswer(fmt.Sprintf("%sTag as%s %s%s%s? (%.2f) [y/N/q] ", theme.Option, theme.Reset, theme.Tag, suggestion.Tag, theme.Reset, suggestion.Score))
+		if err != nil {
+			break
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer == "q" {
+			break
+		}
+		if answer == "y" || answer == "yes" {
+			accepted = append(accepted, suggestion.Tag)
+		}
+	}
+	if len(accepted) == 0 {
+		return existing, false, nil
+	}
+
+	tags := existing
+	for _, tag := range accepted {
+		tags, _ = addTagLine(tags, tag)
+	}
+	if err := setEntryTags(path, tags); err != nil {
+		return nil, false, err
+	}
+	fmt.Println("Tagged " + filepath.Base(path) + ": " + strings.Join(accepted, ", "))
+	return tags, true, nil
+}
+
+// suggest-tags <entry>
+func suggestTagsCommand(args []string) {
+	suggestCmd := flag.NewFlagSet("suggest-tags", flag.ExitOnError)
+	suggestCmd.Parse(args)
+
+	if suggestCmd.NArg() != 1 {
+		fmt.Println("Error: You must provide exactly one entry name")
+		os.Exit(1)
+	}
+	path, err := findEntryPath(suggestCmd.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	tags, changed, err := offerTagSuggestions(path)
+	if err != nil {
+		fmt.Println("Error suggesting tags:", err)
+		os.Exit(1)
+	}
+	if changed {
+		gitCommit(describeEntry("Retag", filepath.Base(path), tags))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestAddTagLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		tag     string
+		want    string
+		changed bool
+	}{
+		{
+			"appends",
+			"## Entry_\nbody\n## _Entry\n## Tags_\nwork\n## _Tags\n",
+			"tax",
+			"## Entry_\nbody\n## _Entry\n## Tags_\nwork\ntax\n## _Tags\n",
+			true,
+		},
+		{
+			"keeps blank lines",
+			"## Tags_\nwork\n\nhome\n\n## _Tags\n",
+			"tax",
+			"## Tags_\nwork\n\nhome\ntax\n\n## _Tags\n",
+			true,
+		},
+		{
+			"keeps tag lines as written",
+			"## Tags_\n  Work/ProjectX \n## _Tags\n",
+			"tax",
+			"## Tags_\n  Work/ProjectX \ntax\n## _Tags\n",
+			true,
+		},
+		{
+			"empty section",
+			"## Tags_\n## _Tags\n",
+			"tax",
+			"## Tags_\ntax\n## _Tags\n",
+			true,
+		},
+		{
+			"already tagged",
+			"## Tags_\nWork\n\n## _Tags\n",
+			"work",
+			"## Tags_\nWork\n\n## _Tags\n",
+			false,
+		},
+		{
+			"crlf",
+			"10/10/2025\r\n---\r\n## Tags_\r\nwork\r\n\r\n## _Tags\r\n",
+			"tax",
+			"10/10/2025\r\n---\r\n## Tags_\r\nwork\r\ntax\r\n\r\n## _Tags\r\n",
+			true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, changed := addTagLine(sectionLines([]byte(test.data), "Tags_", "_Tags"), test.tag)
+			got, err := replaceSection([]byte(test.data), "Tags_", "_Tags", lines)
+			if err != nil {
+				t.Fatalf("replaceSection error: %v", err)
+			}
+			if string(got) != test.want || changed != test.changed {
+				t.Errorf("adding %s = %q, %v, want %q, %v", test.tag, got, changed, test.want, test.changed)
+			}
+		})
+	}
+}
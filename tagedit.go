@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Tags_ lines without the ones naming tag (or an alias of it). Tags below it
+// are kept, removing work leaves work/projectx alone
+func removeTagLines(lines []string, tag string) ([]string, bool) {
+	var kept []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" && normalizeTag(line) == normalizeTag(tag) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept, len(kept) != len(lines)
+}
+
+// Adds or removes one tag on each entry, touching nothing but their Tags_
+// sections, and commits once for all of them. Returns the new tags of the
+// entries that changed, by path
+func retagEntries(entries []Entry, tag string, add bool) (map[string][]string, error) {
+	changed := make(map[string][]string)
+	var names []string
+	for _, entry := range entries {
+		lines, err := getLines(entry.Path, "Tags_", "_Tags")
+		if err != nil {
+			return changed, err
+		}
+		edit := removeTagLines
+		if add {
+			edit = addTagLine
+		}
+		tags, ok := edit(lines, tag)
+		if !ok {
+			continue
+		}
+		if err := setEntryTags(entry.Path, tags); err != nil {
+			return changed, fmt.Errorf("%s: %w", entry.Info.Name(), err)
+		}
+		changed[entry.Path] = tags
+		names = append(names, entry.Info.Name())
+	}
+
+	if len(names) > 0 {
+		if add {
+			gitCommit("Tag " + strings.Join(names, ", ") + " [+" + tag + "]")
+		} else {
+			gitCommit("Tag " + strings.Join(names, ", ") + " [-" + tag + "]")
+		}
+	}
+	return changed, nil
+}
+
+// Keeps the tags of listed entries in step after retagEntries
+func refreshTags(entries []Entry, changed map[string][]string) {
+	for i := range entries {
+		if tags, ok := changed[entries[i].Path]; ok {
+			entries[i].Tags = tags
+			if info, err := os.Stat(entries[i].Path); err == nil {
+				entries[i].Info = info
+			}
+		}
+	}
+}
+
+// t+ tag selection / t- tag selection at the prompt. The selection can't
+// be left out, so every listed entry is only retagged when asked for with *
+func promptRetag(command string, args []string, entriesList []Entry) string {
+	if len(args) == 0 || args[0] == "" {
+		return "Name the tag, e.g. " + command + " work 1-3"
+	}
+	tag := normalizeTag(args[0])
+	if len(args) < 2 || strings.TrimSpace(strings.Join(args[1:], "")) == "" {
+		return "Pick the entries, e.g. " + command + " " + tag + " 1-3, or * for all listed"
+	}
+	selection, err := parseSelection(args[1:], len(entriesList))
+	if err != nil {
+		return "Invalid selection: " + err.Error()
+	}
+	selected := selectEntries(entriesList, selection)
+
+	changed, err := retagEntries(selected, tag, command == "t+")
+	refreshTags(resultsList, changed)
+	refreshTags(mergeList, changed)
+	if err != nil {
+		return "Error tagging entries: " + err.Error()
+	}
+	if len(changed) == 0 {
+		if command == "t+" {
+			return "Already tagged " + tag
+		}
+		return "Not tagged " + tag
+	}
+	verb := "Tagged "
+	if command == "t-" {
+		verb = "Untagged "
+	}
+	var names []string
+	for _, entry := range selected {
+		if _, ok := changed[entry.Path]; ok {
+			names = append(names, entry.Info.Name())
+		}
+	}
+	return verb + strings.Join(names, ", ") + " " + tag
+}
+
+// tag add|remove <tag> <entry>... or tag add|remove <tag> -query [find args]
+func tagCommand(args []string) {
+	if len(args) < 3 || (args[0] != "add" && args[0] != "remove") {
+		fmt.Println("Usage: journalz_ro tag add|remove <tag> <entry>...")
+		fmt.Println("       journalz_ro tag add|remove <tag> -query [find flags] <tag>...")
+		os.Exit(1)
+	}
+	tag := normalizeTag(args[1])
+	if tag == "" {
+		fmt.Println("Error: the tag is empty")
+		os.Exit(1)
+	}
+
+	var entries []Entry
+	if args[2] == "-query" {
+		query, err := parseFindArgs(args[3:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		// Unlike find, merges are only retagged when their tags match
+		query.Options.AllMerges = false
+		entries, err = query.search(nil)
+		if err != nil {
+			fmt.Println("Error reading entries:", err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No entries found with these parameters")
+			return
+		}
+	} else {
+		paths := make(map[string]bool)
+		for _, name := range args[2:] {
+			path, err := findEntryPath(name)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			if !paths[path] {
+				paths[path] = true
+				entries = append(entries, Entry{Path: path, Info: info})
+			}
+		}
+	}
+
+	changed, err := retagEntries(entries, tag, args[0] == "add")
+	for _, entry := range entries {
+		if _, ok := changed[entry.Path]; !ok {
+			continue
+		}
+		if args[0] == "add" {
+			fmt.Println(theme.Success+"+"+tag+theme.Reset, filepath.Base(entry.Path))
+		} else {
+			fmt.Println(theme.Warning+"-"+tag+theme.Reset, filepath.Base(entry.Path))
+		}
+	}
+	if err != nil {
+		fmt.Println("Error tagging entries:", err)
+		os.Exit(1)
+	}
+	if len(changed) == 0 {
+		fmt.Println("Nothing to change")
+	}
+}
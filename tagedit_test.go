@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestRemoveTagLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		tag     string
+		want    string
+		changed bool
+	}{
+		{
+			"removes",
+			"## Tags_\nwork\ntax\n## _Tags\n",
+			"tax",
+			"## Tags_\nwork\n## _Tags\n",
+			true,
+		},
+		{
+			"keeps blank lines and tags below",
+			"## Tags_\nwork\n\nwork/projectx\n\n## _Tags\n",
+			"work",
+			"## Tags_\n\nwork/projectx\n\n## _Tags\n",
+			true,
+		},
+		{
+			"matches however it is written",
+			"## Tags_\n  Tax \nhome\n## _Tags\n",
+			"tax",
+			"## Tags_\nhome\n## _Tags\n",
+			true,
+		},
+		{
+			"not tagged",
+			"## Tags_\nwork\n\n## _Tags\n",
+			"tax",
+			"## Tags_\nwork\n\n## _Tags\n",
+			false,
+		},
+		{
+			"crlf",
+			"## Tags_\r\nwork\r\ntax\r\n## _Tags\r\n",
+			"work",
+			"## Tags_\r\ntax\r\n## _Tags\r\n",
+			true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, changed := removeTagLines(sectionLines([]byte(test.data), "Tags_", "_Tags"), test.tag)
+			got, err := replaceSection([]byte(test.data), "Tags_", "_Tags", lines)
+			if err != nil {
+				t.Fatalf("replaceSection error: %v", err)
+			}
+			if string(got) != test.want || changed != test.changed {
+				t.Errorf("removing %s = %q, %v, want %q, %v", test.tag, got, changed, test.want, test.changed)
+			}
+		})
+	}
+}
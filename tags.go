@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var tagsPath string = filepath.Join(configHome(), "tags.cfg")
+
+// alias -> canonical tag, filled from the tag vocabulary file
+var tagAliases map[string]string = make(map[string]string)
+
+// Reads the tag vocabulary. Each line is canonical=alias,alias...
+// e.g. javascript=js,ecmascript
+// A missing file just means no aliases.
+func loadTagVocabulary(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Ignore empty lines and comments
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid tag vocabulary line: %s", line)
+		}
+
+		canonical := cleanTag(parts[0])
+		if canonical == "" {
+			return fmt.Errorf("invalid tag vocabulary line: %s", line)
+		}
+		for _, alias := range strings.Split(parts[1], ",") {
+			alias = cleanTag(alias)
+			if alias != "" && alias != canonical {
+				tagAliases[alias] = canonical
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Lowercase, trim and drop empty path segments so "Work//ProjectX/" == "work/projectx"
+func cleanTag(tag string) string {
+	var segments []string
+	for _, seg := range strings.Split(strings.ToLower(strings.TrimSpace(tag)), "/") {
+		seg = strings.TrimSpace(seg)
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Returns the canonical form of a tag. A whole-tag alias wins, otherwise
+// each segment of a hierarchical tag is resolved on its own
+func normalizeTag(tag string) string {
+	tag = cleanTag(tag)
+	if canonical, ok := tagAliases[tag]; ok {
+		return canonical
+	}
+	segments := strings.Split(tag, "/")
+	for i, seg := range segments {
+		if canonical, ok := tagAliases[seg]; ok {
+			segments[i] = canonical
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// A tag matches a search tag if it is the same tag or one of its descendants
+// e.g. searching "work" matches "work/projectx/infra"
+func tagMatches(tag string, searchTag string) bool {
+	return tag == searchTag || strings.HasPrefix(tag, searchTag+"/")
+}
+
+// "work/projectx/infra" -> work, work/projectx, work/projectx/infra
+func tagAncestors(tag string) []string {
+	var ancestors []string
+	segments := strings.Split(tag, "/")
+	for i := range segments {
+		ancestors = append(ancestors, strings.Join(segments[:i+1], "/"))
+	}
+	return ancestors
+}
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag != "" && !contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// Hidden directories (.git and the like) are not part of the journal,
+// except for the merge directory
+func skipDir(path string, info os.FileInfo) bool {
+	if !info.IsDir() || !strings.HasPrefix(info.Name(), ".") {
+		return false
+	}
+	path = filepath.Clean(path)
+	return path != filepath.Clean(SAVEDIR) && path != filepath.Clean(MERGE_DIR)
+}
+
+// True for files under MERGE_DIR, wherever it is configured to live
+func isMergePath(path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(MERGE_DIR), path)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// Every entry and merge in the journal
+func loadEntries() ([]Entry, error) {
+	var entries []Entry
+	roots := []string{SAVEDIR}
+	// MERGE_DIR may be configured outside SAVEDIR, walk it separately then
+	if !strings.HasPrefix(filepath.Clean(MERGE_DIR)+"/", filepath.Clean(SAVEDIR)+"/") {
+		roots = append(roots, MERGE_DIR)
+	}
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if skipDir(path, info) {
+				return filepath.SkipDir
+			}
+			if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+				return nil
+			}
+
+			if !askPassphrase && !journalKeyAtHand() && isEncryptedFile(path) {
+				return nil
+			}
+			tags, err := getLines(path, "Tags_", "_Tags")
+			if err != nil {
+				return err
+			}
+			entry := Entry{Path: path, Info: info, MergeOriginals: nil, Tags: tags}
+			if isMergePath(path) {
+				originalEntries, err := getLines(path, "Originals_", "_Originals")
+				if err != nil {
+					return err
+				}
+				entry.MergeOriginals = originalEntries
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// loadEntries for lookups on the side that shouldn't stop to ask for the
+// passphrase, encrypted entries are left out unless the key is known
+func loadUnlockedEntries() ([]Entry, error) {
+	ask := askPassphrase
+	askPassphrase = false
+	defer func() { askPassphrase = ask }()
+	return loadEntries()
+}
+func listTags(args []string) {
+	tagsCmd := flag.NewFlagSet("tags", flag.ExitOnError)
+	flat := tagsCmd.Bool("f", false, "Flat list, do not roll hierarchical tags up into their parents")
+	tagsCmd.Parse(args)
+
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Println("Error reading entries:", err)
+		os.Exit(1)
+	}
+
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		// Count each tag once per entry, even if a parent is reached twice
+		seen := make(map[string]bool)
+		for _, tag := range normalizeTags(entry.Tags) {
+			names := []string{tag}
+			if !*flat {
+				names = tagAncestors(tag)
+			}
+			for _, name := range names {
+				if !seen[name] {
+					seen[name] = true
+					counts[name]++
+				}
+			}
+		}
+	}
+
+	if len(counts) == 0 {
+		fmt.Println("No tags found")
+		return
+	}
+
+	var tags []string
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		name := tag
+		indent := ""
+		if !*flat {
+			depth := strings.Count(tag, "/")
+			indent = strings.Repeat("  ", depth)
+			name = tag[strings.LastIndex(tag, "/")+1:]
+		}
+		fmt.Println(indent+theme.Tag+name+theme.Reset, "("+strconv.Itoa(counts[tag])+")")
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// What each part of the output is drawn in. Fields are whole escape
+// sequences, so a theme with every field empty prints plain text
+type Theme struct {
+	Heading   string
+	Title     string
+	Banner    string
+	Options   string
+	Option    string
+	Info      string
+	Number    string
+	Text      string
+	Separator string
+	Tag       string
+	Heat      string
+	Emphasis  string
+	Faint     string
+	Success   string
+	Warning   string
+	Error     string
+	Reset     string
+}
+
+// The theme in use, plain until setTheme runs
+var theme Theme
+
+// auto, always or never, from -color
+var colorMode string = "auto"
+
+var themesPath string = filepath.Join(configHome(), "themes.cfg")
+
+var builtinThemes = map[string]Theme{
+	"default": {
+		Heading:   Green,
+		Title:     Blue,
+		Banner:    BgBrightGreen + BrightCyan,
+		Options:   BrightMagenta,
+		Option:    Magenta,
+		Info:      Red,
+		Number:    Bold + Blue,
+		Text:      Green,
+		Separator: Yellow,
+		Tag:       Green,
+		Heat:      BrightGreen,
+		Emphasis:  Bold,
+		Faint:     Dim,
+		Success:   Green,
+		Warning:   Yellow,
+		Error:     Red,
+		Reset:     Reset,
+	},
+	// No bright or yellow foregrounds, they wash out on white
+	"light": {
+		Heading:   Green,
+		Title:     Blue,
+		Banner:    BgBlue + BrightWhite,
+		Options:   Magenta,
+		Option:    Magenta,
+		Info:      Red,
+		Number:    Bold + Blue,
+		Text:      Black,
+		Separator: BrightBlack,
+		Tag:       Green,
+		Heat:      Green,
+		Emphasis:  Bold,
+		Faint:     Dim,
+		Success:   Green,
+		Warning:   Magenta,
+		Error:     Red,
+		Reset:     Reset,
+	},
+	// No color at all, only weight and underline, for monochrome terminals
+	// and anyone who can't tell the default colors apart
+	"mono": {
+		Heading:   Bold,
+		Title:     Bold,
+		Banner:    Invert,
+		Options:   Bold,
+		Option:    Bold,
+		Info:      Bold + Underline,
+		Number:    Bold,
+		Separator: Dim,
+		Tag:       Underline,
+		Emphasis:  Bold,
+		Faint:     Dim,
+		Success:   Bold,
+		Warning:   Bold,
+		Error:     Bold + Underline,
+		Reset:     Reset,
+	},
+}
+
+// Words a themes.cfg color can be made of
+var colorWords = map[string]string{
+	"black": Black, "red": Red, "green": Green, "yellow": Yellow,
+	"blue": Blue, "magenta": Magenta, "cyan": Cyan, "white": White,
+	"bright-black": BrightBlack, "bright-red": BrightRed, "bright-green": BrightGreen, "bright-yellow": BrightYellow,
+	"bright-blue": BrightBlue, "bright-magenta": BrightMagenta, "bright-cyan": BrightCyan, "bright-white": BrightWhite,
+	"on-black": BgBlack, "on-red": BgRed, "on-green": BgGreen, "on-yellow": BgYellow,
+	"on-blue": BgBlue, "on-magenta": BgMagenta, "on-cyan": BgCyan, "on-white": BgWhite,
+	"on-bright-black": BgBrightBlack, "on-bright-red": BgBrightRed, "on-bright-green": BgBrightGreen, "on-bright-yellow": BgBrightYellow,
+	"on-bright-blue": BgBrightBlue, "on-bright-magenta": BgBrightMagenta, "on-bright-cyan": BgBrightCyan, "on-bright-white": BgBrightWhite,
+	"bold": Bold, "dim": Dim, "italic": Italic, "underline": Underline,
+	"invert": Invert, "strike": StrikeThrough, "none": "",
+}
+
+// The fields of a theme by the names themes.cfg uses for them
+func (t *Theme) roles() map[string]*string {
+	return map[string]*string{
+		"heading":   &t.Heading,
+		"title":     &t.Title,
+		"banner":    &t.Banner,
+		"options":   &t.Options,
+		"option":    &t.Option,
+		"info":      &t.Info,
+		"number":    &t.Number,
+		"text":      &t.Text,
+		"separator": &t.Separator,
+		"tag":       &t.Tag,
+		"heat":      &t.Heat,
+		"emphasis":  &t.Emphasis,
+		"faint":     &t.Faint,
+		"success":   &t.Success,
+		"warning":   &t.Warning,
+		"error":     &t.Error,
+	}
+}
+
+// Color for -color=always, none for never. auto colors a terminal unless
+// NO_COLOR is set (https://no-color.org)
+func colorEnabled() bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && stdoutIsTerminal()
+}
+
+func stdoutIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Pulls a leading -color=mode (or -color mode) off the arguments
+func takeColorFlag(args []string) (string, []string, bool) {
+	for _, prefix := range []string{"-color", "--color"} {
+		if len(args) >= 1 && strings.HasPrefix(args[0], prefix+"=") {
+			return args[0][len(prefix)+1:], args[1:], true
+		}
+		if len(args) >= 2 && args[0] == prefix {
+			return args[1], args[2:], true
+		}
+	}
+	return "", args, false
+}
+
+// Loads themes.cfg on top of the built-in themes
+func loadThemes() (map[string]Theme, error) {
+	themes := make(map[string]Theme)
+	for name, t := range builtinThemes {
+		themes[name] = t
+	}
+
+	file, err := os.Open(themesPath)
+	if os.IsNotExist(err) {
+		return themes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// [name] starts a theme, base=other copies one to start from, then
+	// role=words for each part that should look different
+	name := ""
+	var current Theme
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if name != "" {
+				themes[name] = current
+			}
+			name = strings.TrimSpace(line[1 : len(line)-1])
+			current = builtinThemes["default"]
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("%s:%d: expected a [theme] header first", themesPath, lineNumber)
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected role=colors, got %q", themesPath, lineNumber, line)
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.ToLower(strings.TrimSpace(parts[1]))
+
+		if key == "base" {
+			base, ok := themes[value]
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: unknown base theme %s", themesPath, lineNumber, value)
+			}
+			current = base
+			continue
+		}
+		roles := current.roles()
+		field, ok := roles[key]
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unknown role %s (roles: %s)", themesPath, lineNumber, key, strings.Join(sortedKeys(roles), ", "))
+		}
+		escape := ""
+		for _, word := range strings.Fields(value) {
+			code, ok := colorWords[word]
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: unknown color %s (colors: %s)", themesPath, lineNumber, word, strings.Join(sortedKeys(colorWords), ", "))
+			}
+			escape += code
+		}
+		*field = escape
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if name != "" {
+		themes[name] = current
+	}
+	return themes, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Switches to the named theme, or to plain text when color is off. The name
+// is checked either way so a typo doesn't hide until output is a terminal
+func setTheme(name string) error {
+	switch colorMode {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("-color must be auto, always or never, got %q", colorMode)
+	}
+
+	themes, err := loadThemes()
+	if err != nil {
+		return err
+	}
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %s (themes: %s)", name, strings.Join(sortedKeys(themes), ", "))
+	}
+
+	if colorEnabled() {
+		theme = t
+	} else {
+		theme = Theme{}
+	}
+	return nil
+}